@@ -0,0 +1,60 @@
+package apply
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/syncer"
+)
+
+func NewCommand(ctx context.Context) *cobra.Command {
+	var conf *config.Config
+	var plan *syncer.Plan
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s [command]", cmd.CommandNameApply),
+		Short: "Apply the changes of a plan file",
+		PreRunE: func(c *cobra.Command, _ []string) (err error) {
+			confPath, err := c.Flags().GetString(cmd.FlagNameConfigFile)
+			if err != nil {
+				return err
+			}
+			planPath, err := c.Flags().GetString(cmd.FlagNamePlanFile)
+			if err != nil {
+				return err
+			}
+			if conf, err = config.New(confPath, true); err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if err = conf.Validate(); err != nil {
+				return fmt.Errorf("error validating config: %w", err)
+			}
+			if plan, err = syncer.LoadPlan(planPath); err != nil {
+				return fmt.Errorf("error loading plan: %w", err)
+			}
+			return nil
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx, *conf.Sync.Timeout)
+			defer cancel()
+			newSyncer := syncer.NewTraktSyncer
+			if plan.RequiresIMDb() {
+				newSyncer = syncer.NewSyncer
+			}
+			s, err := newSyncer(timeoutCtx, conf)
+			if err != nil {
+				return fmt.Errorf("error creating syncer: %w", err)
+			}
+			if err = s.Apply(timeoutCtx, plan); err != nil {
+				return fmt.Errorf("error applying plan: %w", err)
+			}
+			return nil
+		},
+	}
+	command.Flags().String(cmd.FlagNameConfigFile, cmd.ConfigFileDefault, "path to the config file")
+	command.Flags().String(cmd.FlagNamePlanFile, cmd.PlanFileDefault, "path to the plan file to apply")
+	return command
+}
@@ -1,10 +1,24 @@
 package cmd
 
 const (
+	BackupFileDefault    = "trakt-backup.json"
 	CommandAliasRoot     = "imdb-trakt-sync"
+	CommandNameApply     = "apply"
+	CommandNameBackup    = "backup"
 	CommandNameConfigure = "configure"
+	CommandNameExport    = "export"
+	CommandNamePlan      = "plan"
+	CommandNameRestore   = "restore"
 	CommandNameRoot      = "its"
+	CommandNameStatus    = "status"
 	CommandNameSync      = "sync"
+	CommandNameUndo      = "undo"
 	ConfigFileDefault    = "config.yaml"
+	ExportPathDefault    = "imdb-export"
+	FlagNameBackupFile   = "backup-file"
 	FlagNameConfigFile   = "config-file"
+	FlagNameFormat       = "format"
+	FlagNameOutput       = "output"
+	FlagNamePlanFile     = "plan-file"
+	PlanFileDefault      = "plan.json"
 )
@@ -0,0 +1,74 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/export"
+	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+)
+
+func NewCommand(ctx context.Context) *cobra.Command {
+	var conf *config.Config
+	var format, output string
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s [command]", cmd.CommandNameExport),
+		Short: "Export the IMDb lists, watchlist and ratings to a JSON file or a directory of CSV files",
+		PreRunE: func(c *cobra.Command, _ []string) (err error) {
+			confPath, err := c.Flags().GetString(cmd.FlagNameConfigFile)
+			if err != nil {
+				return err
+			}
+			if format, err = c.Flags().GetString(cmd.FlagNameFormat); err != nil {
+				return err
+			}
+			if format != export.FormatJSON && format != export.FormatCSV {
+				return fmt.Errorf("flag '%s' must be one of: %s, %s", cmd.FlagNameFormat, export.FormatJSON, export.FormatCSV)
+			}
+			if output, err = c.Flags().GetString(cmd.FlagNameOutput); err != nil {
+				return err
+			}
+			if output == "" {
+				output = cmd.ExportPathDefault
+				if format == export.FormatJSON {
+					output += ".json"
+				}
+			}
+			if conf, err = config.New(confPath, true); err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if err = conf.ValidateIMDb(); err != nil {
+				return fmt.Errorf("error validating config: %w", err)
+			}
+			return nil
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx, *conf.Sync.Timeout)
+			defer cancel()
+			log := logger.NewLogger(os.Stdout)
+			client, err := imdb.NewAPI(timeoutCtx, &conf.IMDb, log)
+			if err != nil {
+				return fmt.Errorf("error creating imdb client: %w", err)
+			}
+			archive, err := export.Create(client, &conf.IMDb, log)
+			if err != nil {
+				return fmt.Errorf("error exporting imdb data: %w", err)
+			}
+			if err = archive.Write(output, format); err != nil {
+				return fmt.Errorf("error writing export: %w", err)
+			}
+			log.Info("exported imdb data", "path", output, "format", format)
+			return nil
+		},
+	}
+	command.Flags().String(cmd.FlagNameConfigFile, cmd.ConfigFileDefault, "path to the config file")
+	command.Flags().String(cmd.FlagNameFormat, export.FormatJSON, "format of the export, either json or csv")
+	command.Flags().String(cmd.FlagNameOutput, "", "path to the json file or csv directory to write, defaults to imdb-export(.json)")
+	return command
+}
@@ -0,0 +1,56 @@
+package plan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/syncer"
+)
+
+func NewCommand(ctx context.Context) *cobra.Command {
+	var conf *config.Config
+	var planPath string
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s [command]", cmd.CommandNamePlan),
+		Short: "Compute the changes a sync would make to Trakt and write them to a plan file",
+		PreRunE: func(c *cobra.Command, _ []string) (err error) {
+			confPath, err := c.Flags().GetString(cmd.FlagNameConfigFile)
+			if err != nil {
+				return err
+			}
+			if planPath, err = c.Flags().GetString(cmd.FlagNamePlanFile); err != nil {
+				return err
+			}
+			if conf, err = config.New(confPath, true); err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if err = conf.Validate(); err != nil {
+				return fmt.Errorf("error validating config: %w", err)
+			}
+			return nil
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx, *conf.Sync.Timeout)
+			defer cancel()
+			s, err := syncer.NewSyncer(timeoutCtx, conf)
+			if err != nil {
+				return fmt.Errorf("error creating syncer: %w", err)
+			}
+			p, err := s.Plan(timeoutCtx)
+			if err != nil {
+				return fmt.Errorf("error computing plan: %w", err)
+			}
+			if err = p.WriteFile(planPath); err != nil {
+				return fmt.Errorf("error writing plan file: %w", err)
+			}
+			return nil
+		},
+	}
+	command.Flags().String(cmd.FlagNameConfigFile, cmd.ConfigFileDefault, "path to the config file")
+	command.Flags().String(cmd.FlagNamePlanFile, cmd.PlanFileDefault, "path to the plan file to write")
+	return command
+}
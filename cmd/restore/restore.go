@@ -0,0 +1,60 @@
+package restore
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/internal/backup"
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+func NewCommand(ctx context.Context) *cobra.Command {
+	var conf *config.Config
+	var archive *backup.Archive
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s [command]", cmd.CommandNameRestore),
+		Short: "Restore the Trakt watchlist, lists, ratings and history from a backup file",
+		PreRunE: func(c *cobra.Command, _ []string) (err error) {
+			confPath, err := c.Flags().GetString(cmd.FlagNameConfigFile)
+			if err != nil {
+				return err
+			}
+			backupPath, err := c.Flags().GetString(cmd.FlagNameBackupFile)
+			if err != nil {
+				return err
+			}
+			if conf, err = config.New(confPath, true); err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if err = conf.Validate(); err != nil {
+				return fmt.Errorf("error validating config: %w", err)
+			}
+			if archive, err = backup.Load(backupPath); err != nil {
+				return fmt.Errorf("error loading backup: %w", err)
+			}
+			return nil
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx, *conf.Sync.Timeout)
+			defer cancel()
+			log := logger.NewLogger(os.Stdout)
+			client, err := trakt.NewAPI(timeoutCtx, conf.Trakt, log)
+			if err != nil {
+				return fmt.Errorf("error creating trakt client: %w", err)
+			}
+			if err = backup.Restore(timeoutCtx, client, log, archive); err != nil {
+				return fmt.Errorf("error restoring backup: %w", err)
+			}
+			return nil
+		},
+	}
+	command.Flags().String(cmd.FlagNameConfigFile, cmd.ConfigFileDefault, "path to the config file")
+	command.Flags().String(cmd.FlagNameBackupFile, cmd.BackupFileDefault, "path to the backup file to restore")
+	return command
+}
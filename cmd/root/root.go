@@ -7,8 +7,15 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/cmd/apply"
+	"github.com/cecobask/imdb-trakt-sync/cmd/backup"
 	"github.com/cecobask/imdb-trakt-sync/cmd/configure"
+	"github.com/cecobask/imdb-trakt-sync/cmd/export"
+	"github.com/cecobask/imdb-trakt-sync/cmd/plan"
+	"github.com/cecobask/imdb-trakt-sync/cmd/restore"
+	"github.com/cecobask/imdb-trakt-sync/cmd/status"
 	"github.com/cecobask/imdb-trakt-sync/cmd/sync"
+	"github.com/cecobask/imdb-trakt-sync/cmd/undo"
 )
 
 func NewCommand(ctx context.Context) *cobra.Command {
@@ -32,8 +39,15 @@ func NewCommand(ctx context.Context) *cobra.Command {
 		Hidden: true,
 	})
 	command.AddCommand(
+		apply.NewCommand(ctx),
+		backup.NewCommand(ctx),
 		configure.NewCommand(ctx),
+		export.NewCommand(ctx),
+		plan.NewCommand(ctx),
+		restore.NewCommand(ctx),
+		status.NewCommand(ctx),
 		sync.NewCommand(ctx),
+		undo.NewCommand(ctx),
 	)
 	command.SetOut(os.Stdout)
 	command.SetErr(os.Stderr)
@@ -0,0 +1,112 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
+)
+
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+)
+
+func NewCommand(_ context.Context) *cobra.Command {
+	var st *state.State
+	var format string
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s [command]", cmd.CommandNameStatus),
+		Short: "Show the items that Trakt could not find during the previous syncs",
+		PreRunE: func(c *cobra.Command, _ []string) (err error) {
+			confPath, err := c.Flags().GetString(cmd.FlagNameConfigFile)
+			if err != nil {
+				return err
+			}
+			if format, err = c.Flags().GetString(cmd.FlagNameFormat); err != nil {
+				return err
+			}
+			if format != formatTable && format != formatJSON {
+				return fmt.Errorf("flag '%s' must be one of: %s, %s", cmd.FlagNameFormat, formatTable, formatJSON)
+			}
+			conf, err := config.New(confPath, true)
+			if err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if st, err = state.Load(*conf.Sync.StateFile); err != nil {
+				return fmt.Errorf("error loading sync state: %w", err)
+			}
+			return nil
+		},
+		RunE: func(c *cobra.Command, _ []string) error {
+			if format == formatJSON {
+				return writeJSON(c.OutOrStdout(), st)
+			}
+			return writeTable(c.OutOrStdout(), st)
+		},
+	}
+	command.Flags().String(cmd.FlagNameConfigFile, cmd.ConfigFileDefault, "path to the config file")
+	command.Flags().String(cmd.FlagNameFormat, formatTable, "format of the status, either table or json")
+	return command
+}
+
+func writeJSON(w io.Writer, st *state.State) error {
+	notFound := st.NotFound
+	if notFound == nil {
+		notFound = make(state.NotFounds, 0)
+	}
+	b, err := json.MarshalIndent(struct {
+		UpdatedAt time.Time       `json:"updated_at"`
+		NotFound  state.NotFounds `json:"not_found"`
+	}{
+		UpdatedAt: st.UpdatedAt,
+		NotFound:  notFound,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling status: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+func writeTable(w io.Writer, st *state.State) error {
+	var b strings.Builder
+	if st.UpdatedAt.IsZero() {
+		b.WriteString("no sync has been recorded yet\n")
+	} else {
+		fmt.Fprintf(&b, "last sync: %s\n", st.UpdatedAt.Format(time.RFC3339))
+	}
+	if len(st.NotFound) == 0 {
+		b.WriteString("no items are missing on trakt\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	fmt.Fprintf(&b, "items trakt could not find: %d\n\n", len(st.NotFound))
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tTARGET\tTYPE\tIMDB ID\tTITLE\tFIRST SEEN\tLAST SEEN")
+	for _, nf := range st.NotFound {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			nf.Provider,
+			nf.Target,
+			nf.Item.Type,
+			nf.Item.IDs.IMDb,
+			nf.Item.Title,
+			nf.FirstSeenAt.Format(time.DateOnly),
+			nf.LastSeenAt.Format(time.DateOnly),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("error writing status: %w", err)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,55 @@
+package undo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cecobask/imdb-trakt-sync/cmd"
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/syncer"
+)
+
+func NewCommand(ctx context.Context) *cobra.Command {
+	var conf *config.Config
+	var journal *syncer.Journal
+	command := &cobra.Command{
+		Use:   fmt.Sprintf("%s [command]", cmd.CommandNameUndo),
+		Short: "Undo the changes of the last applied sync",
+		PreRunE: func(c *cobra.Command, _ []string) (err error) {
+			confPath, err := c.Flags().GetString(cmd.FlagNameConfigFile)
+			if err != nil {
+				return err
+			}
+			if conf, err = config.New(confPath, true); err != nil {
+				return fmt.Errorf("error loading config: %w", err)
+			}
+			if err = conf.Validate(); err != nil {
+				return fmt.Errorf("error validating config: %w", err)
+			}
+			if journal, err = syncer.LoadJournal(*conf.Sync.JournalFile); err != nil {
+				return fmt.Errorf("error loading journal: %w", err)
+			}
+			return nil
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			timeoutCtx, cancel := context.WithTimeout(ctx, *conf.Sync.Timeout)
+			defer cancel()
+			newSyncer := syncer.NewTraktSyncer
+			if journal.RequiresIMDb() {
+				newSyncer = syncer.NewSyncer
+			}
+			s, err := newSyncer(timeoutCtx, conf)
+			if err != nil {
+				return fmt.Errorf("error creating syncer: %w", err)
+			}
+			if err = s.Undo(timeoutCtx, journal); err != nil {
+				return fmt.Errorf("error undoing last sync: %w", err)
+			}
+			return nil
+		},
+	}
+	command.Flags().String(cmd.FlagNameConfigFile, cmd.ConfigFileDefault, "path to the config file")
+	return command
+}
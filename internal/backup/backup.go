@@ -0,0 +1,218 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+const archiveVersion = 1
+
+// Archive holds everything the syncer can change on trakt, so that it can be
+// pushed back after a sync went wrong.
+type Archive struct {
+	Version   int         `json:"version"`
+	CreatedAt time.Time   `json:"created_at"`
+	Watchlist trakt.Items `json:"watchlist"`
+	Lists     trakt.Lists `json:"lists"`
+	Ratings   trakt.Items `json:"ratings"`
+	History   trakt.Items `json:"history"`
+}
+
+func Create(ctx context.Context, client trakt.API, logger *slog.Logger) (*Archive, error) {
+	watchlist, err := client.WatchlistGet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching trakt watchlist: %w", err)
+	}
+	metas, err := client.ListsGetAllMeta(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching trakt lists metadata: %w", err)
+	}
+	ids := make(trakt.IDMetas, 0, len(metas))
+	for _, meta := range metas {
+		ids = append(ids, meta.IDMeta)
+	}
+	lists, err := client.ListsGet(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching trakt lists: %w", err)
+	}
+	ratings, err := client.RatingsGet(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching trakt ratings: %w", err)
+	}
+	history, err := client.HistoryGetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching trakt history: %w", err)
+	}
+	archive := &Archive{
+		Version:   archiveVersion,
+		CreatedAt: time.Now().UTC(),
+		Watchlist: watchlist.ListItems,
+		Lists:     lists,
+		Ratings:   ratings,
+		History:   history,
+	}
+	logger.Info("backed up trakt data", "watchlist", len(archive.Watchlist), "lists", len(archive.Lists), "ratings", len(archive.Ratings), "history", len(archive.History))
+	return archive, nil
+}
+
+// Restore pushes the archive back to trakt. It only adds what is missing, so
+// anything added to trakt after the archive was created is kept, ratings that
+// were changed since aren't overwritten, and plays that are still in the trakt
+// history aren't added a second time. Lists that don't exist anymore are
+// created again with their description and privacy.
+func Restore(ctx context.Context, client trakt.API, logger *slog.Logger, archive *Archive) error {
+	if len(archive.Watchlist) > 0 {
+		if _, err := client.WatchlistItemsAdd(ctx, archive.Watchlist); err != nil {
+			return fmt.Errorf("failure restoring trakt watchlist: %w", err)
+		}
+	}
+	metas, err := client.ListsGetAllMeta(ctx)
+	if err != nil {
+		return fmt.Errorf("failure fetching trakt lists metadata: %w", err)
+	}
+	existing := make(map[string]int, len(metas))
+	for _, meta := range metas {
+		existing[*meta.Name] = meta.IDMeta.Trakt
+	}
+	for _, list := range archive.Lists {
+		lid, ok := existing[*list.Name]
+		if !ok {
+			idMeta, err := client.ListCreate(ctx, list)
+			if err != nil {
+				return fmt.Errorf("failure creating trakt list %s: %w", *list.Name, err)
+			}
+			lid = idMeta.Trakt
+		}
+		if len(list.ListItems) == 0 {
+			continue
+		}
+		if _, err = client.ListItemsAdd(ctx, lid, *list.Name, list.ListItems); err != nil {
+			return fmt.Errorf("failure restoring trakt list %s: %w", *list.Name, err)
+		}
+	}
+	if len(archive.Ratings) > 0 {
+		ratings, err := client.RatingsGet(ctx)
+		if err != nil {
+			return fmt.Errorf("failure fetching trakt ratings: %w", err)
+		}
+		if missing := missingRatings(archive.Ratings, ratings); len(missing) > 0 {
+			if _, err = client.RatingsAdd(ctx, restorable(missing)); err != nil {
+				return fmt.Errorf("failure restoring trakt ratings: %w", err)
+			}
+		}
+	}
+	if len(archive.History) > 0 {
+		history, err := client.HistoryGetAll(ctx)
+		if err != nil {
+			return fmt.Errorf("failure fetching trakt history: %w", err)
+		}
+		if missing := missingPlays(archive.History, history); len(missing) > 0 {
+			if _, err = client.HistoryAdd(ctx, restorable(missing)); err != nil {
+				return fmt.Errorf("failure restoring trakt history: %w", err)
+			}
+		}
+	}
+	logger.Info("restored trakt data", "createdAt", archive.CreatedAt)
+	return nil
+}
+
+// WriteDir writes the archive to a file in dir that is named after the time
+// the archive was created, so that consecutive backups don't overwrite each other.
+func (a *Archive) WriteDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failure creating backup directory %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("trakt-backup-%s.json", a.CreatedAt.Format("20060102T150405Z")))
+	return path, a.WriteFile(path)
+}
+
+func (a *Archive) WriteFile(path string) error {
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failure marshalling backup: %w", err)
+	}
+	if err = os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("failure writing backup file %s: %w", path, err)
+	}
+	return nil
+}
+
+func Load(path string) (*Archive, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading backup file %s: %w", path, err)
+	}
+	var a Archive
+	if err = json.Unmarshal(b, &a); err != nil {
+		return nil, fmt.Errorf("failure unmarshalling backup file %s: %w", path, err)
+	}
+	if a.Version != archiveVersion {
+		return nil, fmt.Errorf("unsupported backup version %d, expected %d", a.Version, archiveVersion)
+	}
+	return &a, nil
+}
+
+func restorable(its trakt.Items) trakt.Items {
+	restored := make(trakt.Items, 0, len(its))
+	for _, it := range its {
+		restored = append(restored, it.Restorable())
+	}
+	return restored
+}
+
+// missingPlays returns the archived plays that aren't in the trakt history
+// anymore. Plays are told apart by the item that was watched and the time it
+// was watched at, since plays that got removed and added again get a new id.
+func missingPlays(archived, history trakt.Items) trakt.Items {
+	existing := make(map[string]int, len(history))
+	for _, it := range history {
+		existing[playKey(it)]++
+	}
+	missing := make(trakt.Items, 0)
+	for _, it := range archived {
+		key := playKey(it)
+		if existing[key] > 0 {
+			existing[key]--
+			continue
+		}
+		missing = append(missing, it)
+	}
+	return missing
+}
+
+// missingRatings returns the archived ratings of the items that aren't rated
+// on trakt anymore.
+func missingRatings(archived, ratings trakt.Items) trakt.Items {
+	rated := make(map[string]struct{}, len(ratings))
+	for _, it := range ratings {
+		rated[itemKey(it)] = struct{}{}
+	}
+	missing := make(trakt.Items, 0)
+	for _, it := range archived {
+		if _, ok := rated[itemKey(it)]; !ok {
+			missing = append(missing, it)
+		}
+	}
+	return missing
+}
+
+func playKey(it trakt.Item) string {
+	return itemKey(it) + "/" + it.WatchedAt
+}
+
+// itemKey identifies the item by its type and trakt id, which every item
+// fetched from trakt has.
+func itemKey(it trakt.Item) string {
+	var tid int
+	if spec := it.GetItemSpec(); spec != nil {
+		tid = spec.IDMeta.Trakt
+	}
+	return fmt.Sprintf("%s/%d", it.Type, tid)
+}
@@ -0,0 +1,114 @@
+package backup
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+func movie(traktID int, watchedAt string) trakt.Item {
+	return trakt.Item{
+		Type:      trakt.ItemTypeMovie,
+		WatchedAt: watchedAt,
+		Movie:     trakt.ItemSpec{IDMeta: trakt.IDMeta{Trakt: traktID}},
+	}
+}
+
+func episode(traktID int, watchedAt string) trakt.Item {
+	return trakt.Item{
+		Type:      trakt.ItemTypeEpisode,
+		WatchedAt: watchedAt,
+		Episode:   trakt.ItemSpec{IDMeta: trakt.IDMeta{Trakt: traktID}},
+	}
+}
+
+func TestMissingPlays(t *testing.T) {
+	const (
+		monday  = "2024-01-01T20:00:00.000Z"
+		tuesday = "2024-01-02T20:00:00.000Z"
+	)
+	tests := []struct {
+		name     string
+		archived trakt.Items
+		history  trakt.Items
+		want     trakt.Items
+	}{
+		{
+			name:     "empty history",
+			archived: trakt.Items{movie(1, monday), episode(2, tuesday)},
+			history:  trakt.Items{},
+			want:     trakt.Items{movie(1, monday), episode(2, tuesday)},
+		},
+		{
+			name:     "plays still in history are skipped",
+			archived: trakt.Items{movie(1, monday), movie(2, monday)},
+			history:  trakt.Items{movie(1, monday)},
+			want:     trakt.Items{movie(2, monday)},
+		},
+		{
+			name:     "plays at other dates are missing",
+			archived: trakt.Items{movie(1, monday)},
+			history:  trakt.Items{movie(1, tuesday)},
+			want:     trakt.Items{movie(1, monday)},
+		},
+		{
+			name:     "rewatches at the same date are counted",
+			archived: trakt.Items{movie(1, monday), movie(1, monday)},
+			history:  trakt.Items{movie(1, monday)},
+			want:     trakt.Items{movie(1, monday)},
+		},
+		{
+			name:     "items of other types with the same trakt id are told apart",
+			archived: trakt.Items{episode(1, monday)},
+			history:  trakt.Items{movie(1, monday)},
+			want:     trakt.Items{episode(1, monday)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingPlays(tt.archived, tt.history); !slices.EqualFunc(got, tt.want, sameItem) {
+				t.Errorf("missingPlays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingRatings(t *testing.T) {
+	tests := []struct {
+		name     string
+		archived trakt.Items
+		ratings  trakt.Items
+		want     trakt.Items
+	}{
+		{
+			name:     "no current ratings",
+			archived: trakt.Items{movie(1, ""), episode(2, "")},
+			ratings:  trakt.Items{},
+			want:     trakt.Items{movie(1, ""), episode(2, "")},
+		},
+		{
+			name:     "items that are still rated are skipped",
+			archived: trakt.Items{movie(1, ""), movie(2, "")},
+			ratings:  trakt.Items{movie(1, "")},
+			want:     trakt.Items{movie(2, "")},
+		},
+		{
+			name:     "items of other types with the same trakt id are told apart",
+			archived: trakt.Items{episode(1, "")},
+			ratings:  trakt.Items{movie(1, "")},
+			want:     trakt.Items{episode(1, "")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingRatings(tt.archived, tt.ratings); !slices.EqualFunc(got, tt.want, sameItem) {
+				t.Errorf("missingRatings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func sameItem(a, b trakt.Item) bool {
+	return playKey(a) == playKey(b)
+}
@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,8 @@ import (
 )
 
 type IMDb struct {
+	Source       *IMDbSource     `koanf:"SOURCE"`
+	Directory    *string         `koanf:"DIRECTORY"`
 	Auth         *IMDbAuthMethod `koanf:"AUTH"`
 	Email        *string         `koanf:"EMAIL"`
 	Password     *string         `koanf:"PASSWORD"`
@@ -28,44 +32,120 @@ type IMDb struct {
 }
 
 type Trakt struct {
-	ClientID     *string `koanf:"CLIENTID"`
-	ClientSecret *string `koanf:"CLIENTSECRET"`
-	TokenFile    *string `koanf:"TOKENFILE"`
+	ClientID           *string        `koanf:"CLIENTID"`
+	ClientSecret       *string        `koanf:"CLIENTSECRET"`
+	TokenFile          *string        `koanf:"TOKENFILE"`
+	RetryAttempts      *int           `koanf:"RETRYATTEMPTS"`
+	RetryBaseDelay     *time.Duration `koanf:"RETRYBASEDELAY"`
+	RetryMaxDelay      *time.Duration `koanf:"RETRYMAXDELAY"`
+	RetryJitter        *float64       `koanf:"RETRYJITTER"`
+	RetryNetworkErrors *bool          `koanf:"RETRYNETWORKERRORS"`
+	ListParallelism    *int           `koanf:"LISTPARALLELISM"`
+}
+
+// Target configures the mode and scope of an additional sync target. Fields
+// that are left unset fall back to their SYNC_* counterparts.
+type Target struct {
+	Mode      *SyncMode `koanf:"MODE"`
+	Ratings   *bool     `koanf:"RATINGS"`
+	Watchlist *bool     `koanf:"WATCHLIST"`
+	Lists     *bool     `koanf:"LISTS"`
+}
+
+type Letterboxd struct {
+	Directory *string `koanf:"DIRECTORY"`
+	Target    `koanf:",squash"`
 }
 
 type Sync struct {
-	Mode      *SyncMode      `koanf:"MODE"`
-	History   *bool          `koanf:"HISTORY"`
-	Ratings   *bool          `koanf:"RATINGS"`
-	Watchlist *bool          `koanf:"WATCHLIST"`
-	Lists     *bool          `koanf:"LISTS"`
-	Timeout   *time.Duration `koanf:"TIMEOUT"`
+	Mode              *SyncMode           `koanf:"MODE"`
+	Direction         *SyncDirection      `koanf:"DIRECTION"`
+	ConflictPolicy    *SyncConflictPolicy `koanf:"CONFLICTPOLICY"`
+	StateFile         *string             `koanf:"STATEFILE"`
+	JournalFile       *string             `koanf:"JOURNALFILE"`
+	MappingFile       *string             `koanf:"MAPPINGFILE"`
+	Backup            *bool               `koanf:"BACKUP"`
+	BackupDir         *string             `koanf:"BACKUPDIR"`
+	Incremental       *bool               `koanf:"INCREMENTAL"`
+	MaxRemovals       *int                `koanf:"MAXREMOVALS"`
+	MaxRemovalPercent *float64            `koanf:"MAXREMOVALPERCENT"`
+	RemovalGuard      *SyncRemovalGuard   `koanf:"REMOVALGUARD"`
+	History           *bool               `koanf:"HISTORY"`
+	WatchedAt         *SyncWatchedAt      `koanf:"WATCHEDAT"`
+	Rewatches         *bool               `koanf:"REWATCHES"`
+	Ratings           *bool               `koanf:"RATINGS"`
+	Watchlist         *bool               `koanf:"WATCHLIST"`
+	Lists             *bool               `koanf:"LISTS"`
+	IncludedTypes     *[]string           `koanf:"INCLUDEDTYPES"`
+	ExcludedTypes     *[]string           `koanf:"EXCLUDEDTYPES"`
+	TypeMapping       *[]string           `koanf:"TYPEMAPPING"`
+	RatingOffset      *float64            `koanf:"RATINGOFFSET"`
+	RatingMin         *float64            `koanf:"RATINGMIN"`
+	RatingMax         *float64            `koanf:"RATINGMAX"`
+	RatingTable       *[]string           `koanf:"RATINGTABLE"`
+	RatingRounding    *SyncRatingRounding `koanf:"RATINGROUNDING"`
+	NotFoundRetry     *time.Duration      `koanf:"NOTFOUNDRETRY"`
+	Timeout           *time.Duration      `koanf:"TIMEOUT"`
 }
 
 type Config struct {
-	koanf *koanf.Koanf
-	IMDb  IMDb  `koanf:"IMDB"`
-	Trakt Trakt `koanf:"TRAKT"`
-	Sync  Sync  `koanf:"SYNC"`
+	koanf      *koanf.Koanf
+	IMDb       IMDb       `koanf:"IMDB"`
+	Letterboxd Letterboxd `koanf:"LETTERBOXD"`
+	Trakt      Trakt      `koanf:"TRAKT"`
+	Sync       Sync       `koanf:"SYNC"`
 }
 
 const (
-	delimiter = "_"
-	prefix    = "ITS" + delimiter
-
-	IMDbAuthMethodCredentials IMDbAuthMethod = "credentials"
-	IMDbAuthMethodCookies     IMDbAuthMethod = "cookies"
-	IMDbAuthMethodNone        IMDbAuthMethod = "none"
-	SyncModeAddOnly           SyncMode       = "add-only"
-	SyncModeDryRun            SyncMode       = "dry-run"
-	SyncModeFull              SyncMode       = "full"
-	SyncTimeoutDefault                       = time.Minute * 15
+	delimiter  = "_"
+	prefix     = "ITS" + delimiter
+	imdbPrefix = "IMDB" + delimiter
+
+	IMDbAuthMethodCredentials    IMDbAuthMethod     = "credentials"
+	IMDbAuthMethodCookies        IMDbAuthMethod     = "cookies"
+	IMDbAuthMethodNone           IMDbAuthMethod     = "none"
+	IMDbSourceBrowser            IMDbSource         = "browser"
+	IMDbSourceDirectory          IMDbSource         = "directory"
+	SyncConflictPolicyIMDbWins   SyncConflictPolicy = "imdb-wins"
+	SyncConflictPolicyNewestWins SyncConflictPolicy = "newest-wins"
+	SyncConflictPolicySkip       SyncConflictPolicy = "skip"
+	SyncConflictPolicyTraktWins  SyncConflictPolicy = "trakt-wins"
+	SyncDirectionBidirectional   SyncDirection      = "bidirectional"
+	SyncDirectionIMDbToTrakt     SyncDirection      = "imdb-to-trakt"
+	SyncDirectionTraktToIMDb     SyncDirection      = "trakt-to-imdb"
+	SyncModeAddOnly              SyncMode           = "add-only"
+	SyncModeDryRun               SyncMode           = "dry-run"
+	SyncRemovalGuardAbort        SyncRemovalGuard   = "abort"
+	SyncRemovalGuardAddOnly      SyncRemovalGuard   = "add-only"
+	SyncModeFull                 SyncMode           = "full"
+	SyncRatingRoundingDown       SyncRatingRounding = "down"
+	SyncRatingRoundingNearest    SyncRatingRounding = "nearest"
+	SyncRatingRoundingUp         SyncRatingRounding = "up"
+	SyncNotFoundRetryDefault                        = time.Hour * 24 * 7
+	SyncTimeoutDefault                              = time.Minute * 15
+	TraktRetryBaseDelayDefault                      = time.Second
+	TraktRetryMaxDelayDefault                       = time.Minute
+	SyncWatchedAtRated           SyncWatchedAt      = "rated"
+	SyncWatchedAtReleased        SyncWatchedAt      = "released"
+	SyncWatchedAtUnknown         SyncWatchedAt      = "unknown"
 )
 
 type IMDbAuthMethod string
 
+type IMDbSource string
+
 type SyncMode string
 
+type SyncDirection string
+
+type SyncRatingRounding string
+
+type SyncWatchedAt string
+
+type SyncConflictPolicy string
+
+type SyncRemovalGuard string
+
 func New(path string, includeEnv bool) (*Config, error) {
 	k := koanf.New(delimiter)
 	fileProvider := file.Provider(path)
@@ -105,6 +185,91 @@ func NewFromMap(data map[string]interface{}) (*Config, error) {
 }
 
 func (c *Config) Validate() error {
+	if err := c.validateIMDb(); err != nil {
+		return err
+	}
+	if isNilOrEmpty(c.Trakt.ClientID) {
+		return fmt.Errorf("field 'TRAKT_CLIENTID' is required")
+	}
+	if isNilOrEmpty(c.Trakt.ClientSecret) {
+		return fmt.Errorf("field 'TRAKT_CLIENTSECRET' is required")
+	}
+	if c.Sync.Mode == nil || *c.Sync.Mode == "" {
+		return fmt.Errorf("field 'SYNC_MODE' is required")
+	}
+	if !slices.Contains(validSyncModes(), string(*c.Sync.Mode)) {
+		return fmt.Errorf("field 'SYNC_MODE' must be one of: %s", strings.Join(validSyncModes(), ", "))
+	}
+	if err := c.validateSyncDirection(); err != nil {
+		return err
+	}
+	if err := c.validateRemovalGuard(); err != nil {
+		return err
+	}
+	if err := c.validateRatingTransform(); err != nil {
+		return err
+	}
+	if err := c.validateTraktRetries(); err != nil {
+		return err
+	}
+	if err := c.validateTraktListParallelism(); err != nil {
+		return err
+	}
+	if !slices.Contains(validSyncWatchedAts(), string(*c.Sync.WatchedAt)) {
+		return fmt.Errorf("field 'SYNC_WATCHEDAT' must be one of: %s", strings.Join(validSyncWatchedAts(), ", "))
+	}
+	if err := validateTitleTypes("SYNC_INCLUDEDTYPES", *c.Sync.IncludedTypes); err != nil {
+		return err
+	}
+	if err := validateTitleTypes("SYNC_EXCLUDEDTYPES", *c.Sync.ExcludedTypes); err != nil {
+		return err
+	}
+	if _, err := ParseTypeMapping(*c.Sync.TypeMapping); err != nil {
+		return fmt.Errorf("field 'SYNC_TYPEMAPPING' is invalid: %w", err)
+	}
+	if err := c.Letterboxd.validate("LETTERBOXD"); err != nil {
+		return err
+	}
+	return c.checkDummies("")
+}
+
+// ValidateIMDb only validates the imdb fields, for commands that don't need
+// trakt credentials.
+func (c *Config) ValidateIMDb() error {
+	if err := c.validateIMDb(); err != nil {
+		return err
+	}
+	return c.checkDummies(imdbPrefix)
+}
+
+func (c *Config) validateIMDb() error {
+	if err := c.validateIMDbSource(); err != nil {
+		return err
+	}
+	if err := c.validateListIdentifiers(*c.IMDb.Lists); err != nil {
+		return fmt.Errorf("field 'IMDB_LISTS' is invalid: %w", err)
+	}
+	if err := c.validateListIdentifiers(*c.IMDb.IgnoredLists); err != nil {
+		return fmt.Errorf("field 'IMDB_IGNOREDLISTS' is invalid: %w", err)
+	}
+	return nil
+}
+
+func (c *Config) validateIMDbSource() error {
+	switch *c.IMDb.Source {
+	case IMDbSourceBrowser:
+		return c.validateIMDbAuth()
+	case IMDbSourceDirectory:
+		if isNilOrEmpty(c.IMDb.Directory) {
+			return fmt.Errorf("field 'IMDB_DIRECTORY' is required")
+		}
+		return nil
+	default:
+		return fmt.Errorf("field 'IMDB_SOURCE' must be one of: %s", strings.Join(validIMDbSources(), ", "))
+	}
+}
+
+func (c *Config) validateIMDbAuth() error {
 	if c.IMDb.Auth == nil || *c.IMDb.Auth == "" {
 		return fmt.Errorf("field 'IMDB_AUTH' is required")
 	}
@@ -124,25 +289,133 @@ func (c *Config) Validate() error {
 	default:
 		return fmt.Errorf("field 'IMDB_AUTH' must be one of: %s", strings.Join(validIMDbAuthMethods(), ", "))
 	}
-	if err := c.validateListIdentifiers(*c.IMDb.Lists); err != nil {
-		return fmt.Errorf("field 'IMDB_LISTS' is invalid: %w", err)
+	return nil
+}
+
+func (c *Config) validateSyncDirection() error {
+	switch *c.Sync.Direction {
+	case SyncDirectionIMDbToTrakt:
+	case SyncDirectionTraktToIMDb, SyncDirectionBidirectional:
+		if *c.IMDb.Source != IMDbSourceBrowser || *c.IMDb.Auth == IMDbAuthMethodNone {
+			return fmt.Errorf("field 'SYNC_DIRECTION' requires IMDB_SOURCE 'browser' and an IMDB_AUTH other than 'none' to write to imdb")
+		}
+	default:
+		return fmt.Errorf("field 'SYNC_DIRECTION' must be one of: %s", strings.Join(validSyncDirections(), ", "))
 	}
-	if err := c.validateListIdentifiers(*c.IMDb.IgnoredLists); err != nil {
-		return fmt.Errorf("field 'IMDB_IGNOREDLISTS' is invalid: %w", err)
+	if *c.Sync.Direction == SyncDirectionBidirectional && !slices.Contains(validSyncConflictPolicies(), string(*c.Sync.ConflictPolicy)) {
+		return fmt.Errorf("field 'SYNC_CONFLICTPOLICY' must be one of: %s", strings.Join(validSyncConflictPolicies(), ", "))
 	}
-	if isNilOrEmpty(c.Trakt.ClientID) {
-		return fmt.Errorf("field 'TRAKT_CLIENTID' is required")
+	return nil
+}
+
+func (c *Config) validateTraktRetries() error {
+	if *c.Trakt.RetryAttempts < 1 {
+		return fmt.Errorf("field 'TRAKT_RETRYATTEMPTS' must be at least 1")
 	}
-	if isNilOrEmpty(c.Trakt.ClientSecret) {
-		return fmt.Errorf("field 'TRAKT_CLIENTSECRET' is required")
+	if *c.Trakt.RetryBaseDelay <= 0 || *c.Trakt.RetryMaxDelay < *c.Trakt.RetryBaseDelay {
+		return fmt.Errorf("fields 'TRAKT_RETRYBASEDELAY' and 'TRAKT_RETRYMAXDELAY' must be positive, with the base delay not above the max delay")
 	}
-	if c.Sync.Mode == nil || *c.Sync.Mode == "" {
-		return fmt.Errorf("field 'SYNC_MODE' is required")
+	if *c.Trakt.RetryJitter < 0 || *c.Trakt.RetryJitter > 1 {
+		return fmt.Errorf("field 'TRAKT_RETRYJITTER' must be between 0 and 1")
 	}
-	if !slices.Contains(validSyncModes(), string(*c.Sync.Mode)) {
-		return fmt.Errorf("field 'SYNC_MODE' must be one of: %s", strings.Join(validSyncModes(), ", "))
+	return nil
+}
+
+func (c *Config) validateTraktListParallelism() error {
+	if *c.Trakt.ListParallelism < 1 {
+		return fmt.Errorf("field 'TRAKT_LISTPARALLELISM' must be at least 1")
+	}
+	return nil
+}
+
+func (c *Config) validateRatingTransform() error {
+	if *c.Sync.RatingMin < 1 || *c.Sync.RatingMax > 10 || *c.Sync.RatingMin > *c.Sync.RatingMax {
+		return fmt.Errorf("fields 'SYNC_RATINGMIN' and 'SYNC_RATINGMAX' must be between 1 and 10, with the minimum not above the maximum")
+	}
+	if _, err := ParseRatingTable(*c.Sync.RatingTable); err != nil {
+		return fmt.Errorf("field 'SYNC_RATINGTABLE' is invalid: %w", err)
+	}
+	if !slices.Contains(validSyncRatingRoundings(), string(*c.Sync.RatingRounding)) {
+		return fmt.Errorf("field 'SYNC_RATINGROUNDING' must be one of: %s", strings.Join(validSyncRatingRoundings(), ", "))
+	}
+	return nil
+}
+
+// ParseRatingTable parses the entries of SYNC_RATINGTABLE, which map an imdb
+// rating onto a trakt rating in the format imdb:trakt, e.g. 5:6.
+func ParseRatingTable(entries []string) (map[float64]float64, error) {
+	table := make(map[float64]float64, len(entries))
+	for _, entry := range entries {
+		from, to, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("entry %s must have the format imdb:trakt", entry)
+		}
+		imdbRating, err := parseRating(from)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s has an invalid imdb rating: %w", entry, err)
+		}
+		traktRating, err := parseRating(to)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s has an invalid trakt rating: %w", entry, err)
+		}
+		table[imdbRating] = traktRating
+	}
+	return table, nil
+}
+
+func parseRating(value string) (float64, error) {
+	rating, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if rating < 1 || rating > 10 {
+		return 0, fmt.Errorf("rating %d must be between 1 and 10", rating)
+	}
+	return float64(rating), nil
+}
+
+// ParseTypeMapping parses the entries of SYNC_TYPEMAPPING, which map an imdb
+// title type onto a trakt type in the format imdb:trakt, e.g. TV Special:show.
+// Titles mapped onto skip are never synced.
+func ParseTypeMapping(entries []string) (map[string]string, error) {
+	mapping := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		from, to, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("entry %s must have the format imdb:trakt", entry)
+		}
+		titleType, traktType := strings.TrimSpace(from), strings.TrimSpace(to)
+		if !slices.Contains(validIMDbTitleTypes(), titleType) {
+			return nil, fmt.Errorf("entry %s has an unknown imdb title type, which must be one of: %s", entry, strings.Join(validIMDbTitleTypes(), ", "))
+		}
+		if !slices.Contains(validSyncMappedTypes(), traktType) {
+			return nil, fmt.Errorf("entry %s has an unknown trakt type, which must be one of: %s", entry, strings.Join(validSyncMappedTypes(), ", "))
+		}
+		mapping[titleType] = traktType
 	}
-	return c.checkDummies()
+	return mapping, nil
+}
+
+func validateTitleTypes(field string, titleTypes []string) error {
+	for _, titleType := range titleTypes {
+		if !slices.Contains(validIMDbTitleTypes(), titleType) {
+			return fmt.Errorf("field '%s' must only contain: %s", field, strings.Join(validIMDbTitleTypes(), ", "))
+		}
+	}
+	return nil
+}
+
+func (c *Config) validateRemovalGuard() error {
+	if *c.Sync.MaxRemovals < 0 {
+		return fmt.Errorf("field 'SYNC_MAXREMOVALS' must not be negative")
+	}
+	if *c.Sync.MaxRemovalPercent < 0 || *c.Sync.MaxRemovalPercent > 100 {
+		return fmt.Errorf("field 'SYNC_MAXREMOVALPERCENT' must be between 0 and 100")
+	}
+	if !slices.Contains(validSyncRemovalGuards(), string(*c.Sync.RemovalGuard)) {
+		return fmt.Errorf("field 'SYNC_REMOVALGUARD' must be one of: %s", strings.Join(validSyncRemovalGuards(), ", "))
+	}
+	return nil
 }
 
 func (c *Config) validateListIdentifiers(lids []string) error {
@@ -167,8 +440,12 @@ func (c *Config) Flatten() map[string]interface{} {
 	return c.koanf.All()
 }
 
-func (c *Config) checkDummies() error {
+// checkDummies checks the fields whose key starts with keyPrefix.
+func (c *Config) checkDummies(keyPrefix string) error {
 	for k, v := range c.koanf.All() {
+		if !strings.HasPrefix(k, keyPrefix) {
+			continue
+		}
 		if value, ok := v.(string); ok {
 			if match := slices.Contains(dummyValues(), value); match {
 				return fmt.Errorf("field '%s' contains dummy value '%s'", k, value)
@@ -189,6 +466,12 @@ func (c *Config) checkDummies() error {
 }
 
 func (c *Config) applyDefaults() {
+	if c.IMDb.Source == nil || *c.IMDb.Source == "" {
+		c.IMDb.Source = pointer(IMDbSourceBrowser)
+	}
+	if c.IMDb.Directory == nil {
+		c.IMDb.Directory = pointer("")
+	}
 	if c.IMDb.Auth == nil {
 		c.IMDb.Auth = pointer(IMDbAuthMethodCookies)
 	}
@@ -210,12 +493,69 @@ func (c *Config) applyDefaults() {
 	if c.Trakt.TokenFile == nil || *c.Trakt.TokenFile == "" {
 		c.Trakt.TokenFile = pointer("trakt-token.json")
 	}
+	if c.Trakt.RetryAttempts == nil {
+		c.Trakt.RetryAttempts = pointer(5)
+	}
+	if c.Trakt.RetryBaseDelay == nil {
+		c.Trakt.RetryBaseDelay = pointer(TraktRetryBaseDelayDefault)
+	}
+	if c.Trakt.RetryMaxDelay == nil {
+		c.Trakt.RetryMaxDelay = pointer(TraktRetryMaxDelayDefault)
+	}
+	if c.Trakt.RetryJitter == nil {
+		c.Trakt.RetryJitter = pointer(0.2)
+	}
+	if c.Trakt.RetryNetworkErrors == nil {
+		c.Trakt.RetryNetworkErrors = pointer(true)
+	}
+	if c.Trakt.ListParallelism == nil {
+		c.Trakt.ListParallelism = pointer(4)
+	}
 	if c.Sync.Mode == nil {
 		c.Sync.Mode = pointer(SyncModeDryRun)
 	}
+	if c.Sync.Direction == nil || *c.Sync.Direction == "" {
+		c.Sync.Direction = pointer(SyncDirectionIMDbToTrakt)
+	}
+	if c.Sync.ConflictPolicy == nil || *c.Sync.ConflictPolicy == "" {
+		c.Sync.ConflictPolicy = pointer(SyncConflictPolicySkip)
+	}
+	if c.Sync.StateFile == nil || *c.Sync.StateFile == "" {
+		c.Sync.StateFile = pointer(filepath.Join(filepath.Dir(*c.Trakt.TokenFile), "sync-state.json"))
+	}
+	if c.Sync.JournalFile == nil || *c.Sync.JournalFile == "" {
+		c.Sync.JournalFile = pointer(filepath.Join(filepath.Dir(*c.Trakt.TokenFile), "sync-journal.json"))
+	}
+	if c.Sync.MappingFile == nil {
+		c.Sync.MappingFile = pointer("")
+	}
+	if c.Sync.Backup == nil {
+		c.Sync.Backup = pointer(true)
+	}
+	if c.Sync.BackupDir == nil || *c.Sync.BackupDir == "" {
+		c.Sync.BackupDir = pointer(filepath.Join(filepath.Dir(*c.Trakt.TokenFile), "trakt-backups"))
+	}
+	if c.Sync.Incremental == nil {
+		c.Sync.Incremental = pointer(true)
+	}
+	if c.Sync.MaxRemovals == nil {
+		c.Sync.MaxRemovals = pointer(0)
+	}
+	if c.Sync.MaxRemovalPercent == nil {
+		c.Sync.MaxRemovalPercent = pointer(50.0)
+	}
+	if c.Sync.RemovalGuard == nil || *c.Sync.RemovalGuard == "" {
+		c.Sync.RemovalGuard = pointer(SyncRemovalGuardAbort)
+	}
 	if c.Sync.History == nil {
 		c.Sync.History = pointer(false)
 	}
+	if c.Sync.WatchedAt == nil || *c.Sync.WatchedAt == "" {
+		c.Sync.WatchedAt = pointer(SyncWatchedAtRated)
+	}
+	if c.Sync.Rewatches == nil {
+		c.Sync.Rewatches = pointer(false)
+	}
 	if c.Sync.Ratings == nil {
 		c.Sync.Ratings = pointer(true)
 	}
@@ -225,9 +565,64 @@ func (c *Config) applyDefaults() {
 	if c.Sync.Lists == nil {
 		c.Sync.Lists = pointer(true)
 	}
+	if c.Sync.IncludedTypes == nil {
+		c.Sync.IncludedTypes = pointer(make([]string, 0))
+	}
+	if c.Sync.ExcludedTypes == nil {
+		c.Sync.ExcludedTypes = pointer(make([]string, 0))
+	}
+	if c.Sync.TypeMapping == nil {
+		c.Sync.TypeMapping = pointer(make([]string, 0))
+	}
+	if c.Sync.RatingOffset == nil {
+		c.Sync.RatingOffset = pointer(0.0)
+	}
+	if c.Sync.RatingMin == nil {
+		c.Sync.RatingMin = pointer(1.0)
+	}
+	if c.Sync.RatingMax == nil {
+		c.Sync.RatingMax = pointer(10.0)
+	}
+	if c.Sync.RatingTable == nil {
+		c.Sync.RatingTable = pointer(make([]string, 0))
+	}
+	if c.Sync.RatingRounding == nil || *c.Sync.RatingRounding == "" {
+		c.Sync.RatingRounding = pointer(SyncRatingRoundingNearest)
+	}
+	if c.Sync.NotFoundRetry == nil {
+		c.Sync.NotFoundRetry = pointer(SyncNotFoundRetryDefault)
+	}
 	if c.Sync.Timeout == nil {
 		c.Sync.Timeout = pointer(SyncTimeoutDefault)
 	}
+	if c.Letterboxd.Directory == nil {
+		c.Letterboxd.Directory = pointer("")
+	}
+	c.Letterboxd.applyDefaults(c.Sync)
+}
+
+// applyDefaults must run after the sync defaults were applied, since the
+// target inherits them.
+func (t *Target) applyDefaults(sync Sync) {
+	if t.Mode == nil || *t.Mode == "" {
+		t.Mode = pointer(*sync.Mode)
+	}
+	if t.Ratings == nil {
+		t.Ratings = pointer(*sync.Ratings)
+	}
+	if t.Watchlist == nil {
+		t.Watchlist = pointer(*sync.Watchlist)
+	}
+	if t.Lists == nil {
+		t.Lists = pointer(*sync.Lists)
+	}
+}
+
+func (t *Target) validate(name string) error {
+	if !slices.Contains(validSyncModes(), string(*t.Mode)) {
+		return fmt.Errorf("field '%s_MODE' must be one of: %s", name, strings.Join(validSyncModes(), ", "))
+	}
+	return nil
 }
 
 func pointer[T any](v T) *T {
@@ -242,6 +637,53 @@ func validSyncModes() []string {
 	}
 }
 
+func validSyncDirections() []string {
+	return []string{
+		string(SyncDirectionIMDbToTrakt),
+		string(SyncDirectionTraktToIMDb),
+		string(SyncDirectionBidirectional),
+	}
+}
+
+func validSyncRemovalGuards() []string {
+	return []string{
+		string(SyncRemovalGuardAbort),
+		string(SyncRemovalGuardAddOnly),
+	}
+}
+
+func validSyncRatingRoundings() []string {
+	return []string{
+		string(SyncRatingRoundingNearest),
+		string(SyncRatingRoundingUp),
+		string(SyncRatingRoundingDown),
+	}
+}
+
+func validSyncWatchedAts() []string {
+	return []string{
+		string(SyncWatchedAtRated),
+		string(SyncWatchedAtReleased),
+		string(SyncWatchedAtUnknown),
+	}
+}
+
+func validSyncConflictPolicies() []string {
+	return []string{
+		string(SyncConflictPolicySkip),
+		string(SyncConflictPolicyIMDbWins),
+		string(SyncConflictPolicyTraktWins),
+		string(SyncConflictPolicyNewestWins),
+	}
+}
+
+func validIMDbSources() []string {
+	return []string{
+		string(IMDbSourceBrowser),
+		string(IMDbSourceDirectory),
+	}
+}
+
 func validIMDbAuthMethods() []string {
 	return []string{
 		string(IMDbAuthMethodCredentials),
@@ -250,6 +692,38 @@ func validIMDbAuthMethods() []string {
 	}
 }
 
+// validIMDbTitleTypes lists the title types of the imdb exports.
+func validIMDbTitleTypes() []string {
+	return []string{
+		"Movie",
+		"Music Video",
+		"Person",
+		"Podcast Episode",
+		"Podcast Series",
+		"Short",
+		"TV Episode",
+		"TV Mini Series",
+		"TV Movie",
+		"TV Series",
+		"TV Short",
+		"TV Special",
+		"Video",
+		"Video Game",
+	}
+}
+
+// validSyncMappedTypes lists the trakt types that imdb title types can be
+// mapped onto, as well as skip, which leaves them out of the sync.
+func validSyncMappedTypes() []string {
+	return []string{
+		"episode",
+		"movie",
+		"person",
+		"show",
+		"skip",
+	}
+}
+
 func dummyValues() []string {
 	return []string{
 		"user@domain.com",
@@ -280,6 +754,10 @@ func sliceFields() []string {
 	return []string{
 		"IMDB_LISTS",
 		"IMDB_IGNOREDLISTS",
+		"SYNC_INCLUDEDTYPES",
+		"SYNC_EXCLUDEDTYPES",
+		"SYNC_TYPEMAPPING",
+		"SYNC_RATINGTABLE",
 	}
 }
 
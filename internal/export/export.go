@@ -0,0 +1,92 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
+)
+
+const (
+	archiveVersion = 1
+
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// Archive holds the imdb lists, watchlist and ratings with every column of the
+// imdb exports.
+type Archive struct {
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	Watchlist *imdb.List `json:"watchlist,omitempty"`
+	Lists     imdb.Lists `json:"lists"`
+	Ratings   imdb.Items `json:"ratings,omitempty"`
+}
+
+// Create exports and fetches the imdb data. The watchlist and ratings are left
+// out when no imdb auth was provided, since they can't be exported without it.
+func Create(client imdb.API, conf *config.IMDb, logger *slog.Logger) (*Archive, error) {
+	authless := *conf.Source == config.IMDbSourceBrowser && *conf.Auth == config.IMDbAuthMethodNone
+	archive := &Archive{
+		Version:   archiveVersion,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := client.ListsExport(*conf.Lists...); err != nil {
+		return nil, fmt.Errorf("failure exporting imdb lists: %w", err)
+	}
+	lists, err := client.ListsGet(*conf.Lists...)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching imdb lists: %w", err)
+	}
+	archive.Lists = lists
+	if authless {
+		logger.Info("skipping export of imdb watchlist and ratings since no imdb auth was provided")
+		return archive, nil
+	}
+	if err = client.WatchlistExport(); err != nil {
+		return nil, fmt.Errorf("failure exporting imdb watchlist: %w", err)
+	}
+	if archive.Watchlist, err = client.WatchlistGet(); err != nil {
+		return nil, fmt.Errorf("failure fetching imdb watchlist: %w", err)
+	}
+	if err = client.RatingsExport(); err != nil {
+		return nil, fmt.Errorf("failure exporting imdb ratings: %w", err)
+	}
+	if archive.Ratings, err = client.RatingsGet(); err != nil {
+		return nil, fmt.Errorf("failure fetching imdb ratings: %w", err)
+	}
+	if archive.Ratings == nil {
+		archive.Ratings = make(imdb.Items, 0)
+	}
+	return archive, nil
+}
+
+// Write writes the archive as a json file, or as a directory of csv files in
+// the format of the imdb exports, which can be used as IMDB_DIRECTORY.
+func (a *Archive) Write(path, format string) error {
+	switch format {
+	case FormatJSON:
+		b, err := json.MarshalIndent(a, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failure marshalling export: %w", err)
+		}
+		if err = os.WriteFile(path, b, 0o644); err != nil {
+			return fmt.Errorf("failure writing export file %s: %w", path, err)
+		}
+		return nil
+	case FormatCSV:
+		lists := slices.Clone(a.Lists)
+		if a.Watchlist != nil {
+			lists = append(lists, *a.Watchlist)
+		}
+		return imdb.WriteDirectory(path, lists, a.Ratings)
+	default:
+		return fmt.Errorf("unsupported export format %s, expected one of: %s, %s", format, FormatJSON, FormatCSV)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"slices"
 	"strconv"
 	"strings"
@@ -22,12 +23,19 @@ import (
 )
 
 type API interface {
+	EpisodesGet(ctx context.Context, ids ...string) (Episodes, error)
+	ListItemsAdd(id string, its Items) error
+	ListItemsRemove(id string, its Items) error
 	ListsExport(ids ...string) error
 	ListsGet(ids ...string) (Lists, error)
 	WatchlistExport() error
 	WatchlistGet() (*List, error)
+	WatchlistItemsAdd(its Items) error
+	WatchlistItemsRemove(its Items) error
+	RatingsAdd(its Items) error
 	RatingsExport() error
 	RatingsGet() (Items, error)
+	RatingsRemove(its Items) error
 }
 
 const (
@@ -37,6 +45,7 @@ const (
 
 	pathBase      = "https://imdb.com"
 	pathExports   = "/exports"
+	pathGraphQL   = "https://api.graphql.imdb.com/"
 	pathList      = "/list/%s"
 	pathLists     = "/profile/lists"
 	pathRatings   = "/user/%s/ratings"
@@ -51,6 +60,22 @@ const (
 	selectorNextData           = "#__NEXT_DATA__"
 	selectorPrivateListContent = "div[data-testid='list-page-mc-private-list-content']"
 	selectorWAF                = "script[src*='token.awswaf.com']"
+
+	// The mutations below are the ones imdb's own website sends when a title
+	// is rated or added to a list, so they're authorized by the browser session.
+	mutationAddToList           = `mutation AddConstToList($listId: ID!, $const: ID!) { addItemToList(input: {listId: $listId, item: {itemElementId: $const}}) { listId } }`
+	mutationAddToWatchlist      = `mutation AddConstToWatchlist($const: ID!) { addItemToPredefinedList(input: {classType: WATCH_LIST, item: {itemElementId: $const}}) { listId } }`
+	mutationDeleteRating        = `mutation DeleteTitleRating($const: ID!) { deleteTitleRating(input: {titleId: $const}) { date } }`
+	mutationRateTitle           = `mutation UpdateTitleRating($const: ID!, $rating: Int!) { rateTitle(input: {titleId: $const, rating: $rating}) { rating { value } } }`
+	mutationRemoveFromList      = `mutation RemoveConstFromList($listId: ID!, $const: ID!) { removeElementFromList(input: {listId: $listId, itemElementId: $const}) { listId } }`
+	mutationRemoveFromWatchlist = `mutation RemoveConstFromWatchlist($const: ID!) { removeElementFromPredefinedList(input: {classType: WATCH_LIST, itemElementId: $const}) { listId } }`
+
+	// scriptGraphQL sends a graphql request from within an imdb tab, so that the
+	// session cookies are attached to it the same way they would be by imdb itself.
+	scriptGraphQL = `async (url, body) => {
+		const response = await fetch(url, {method: "POST", credentials: "include", headers: {"content-type": "application/json"}, body});
+		return {status: response.status, body: await response.text()};
+	}`
 )
 
 type client struct {
@@ -64,6 +89,9 @@ type client struct {
 }
 
 func NewAPI(ctx context.Context, conf *config.IMDb, logger *slog.Logger) (API, error) {
+	if *conf.Source == config.IMDbSourceDirectory {
+		return newDirectoryClient(conf, logger)
+	}
 	l := launcher.New().Headless(*conf.Headless).Bin(getBrowserPathOrFallback(*conf.BrowserPath)).
 		Set("allow-running-insecure-content").
 		Set("autoplay-policy", "user-gesture-required").
@@ -351,6 +379,136 @@ func (c *client) RatingsGet() (Items, error) {
 	return c.ratingsDownload(filteredResources[0])
 }
 
+func (c *client) EpisodesGet(ctx context.Context, ids ...string) (Episodes, error) {
+	return episodesGet(ctx, ids...)
+}
+
+func (c *client) ListItemsAdd(id string, its Items) error {
+	requests := make([]GraphQLRequest, 0, len(its))
+	for _, it := range its {
+		requests = append(requests, GraphQLRequest{
+			Query:     mutationAddToList,
+			Variables: map[string]any{"listId": id, "const": it.ID},
+		})
+	}
+	if err := c.doGraphQLRequests(requests); err != nil {
+		return fmt.Errorf("failure adding items to imdb list %s: %w", id, err)
+	}
+	c.logger.Info("added imdb list items", "id", id, "count", len(requests))
+	return nil
+}
+
+func (c *client) ListItemsRemove(id string, its Items) error {
+	requests := make([]GraphQLRequest, 0, len(its))
+	for _, it := range its {
+		requests = append(requests, GraphQLRequest{
+			Query:     mutationRemoveFromList,
+			Variables: map[string]any{"listId": id, "const": it.ID},
+		})
+	}
+	if err := c.doGraphQLRequests(requests); err != nil {
+		return fmt.Errorf("failure removing items from imdb list %s: %w", id, err)
+	}
+	c.logger.Info("removed imdb list items", "id", id, "count", len(requests))
+	return nil
+}
+
+func (c *client) WatchlistItemsAdd(its Items) error {
+	requests := make([]GraphQLRequest, 0, len(its))
+	for _, it := range its {
+		requests = append(requests, GraphQLRequest{
+			Query:     mutationAddToWatchlist,
+			Variables: map[string]any{"const": it.ID},
+		})
+	}
+	if err := c.doGraphQLRequests(requests); err != nil {
+		return fmt.Errorf("failure adding items to imdb watchlist: %w", err)
+	}
+	c.logger.Info("added imdb watchlist items", "count", len(requests))
+	return nil
+}
+
+func (c *client) WatchlistItemsRemove(its Items) error {
+	requests := make([]GraphQLRequest, 0, len(its))
+	for _, it := range its {
+		requests = append(requests, GraphQLRequest{
+			Query:     mutationRemoveFromWatchlist,
+			Variables: map[string]any{"const": it.ID},
+		})
+	}
+	if err := c.doGraphQLRequests(requests); err != nil {
+		return fmt.Errorf("failure removing items from imdb watchlist: %w", err)
+	}
+	c.logger.Info("removed imdb watchlist items", "count", len(requests))
+	return nil
+}
+
+func (c *client) RatingsAdd(its Items) error {
+	requests := make([]GraphQLRequest, 0, len(its))
+	for _, it := range its {
+		if it.Rating == nil {
+			continue
+		}
+		requests = append(requests, GraphQLRequest{
+			Query:     mutationRateTitle,
+			Variables: map[string]any{"const": it.ID, "rating": int(*it.Rating)},
+		})
+	}
+	if err := c.doGraphQLRequests(requests); err != nil {
+		return fmt.Errorf("failure adding imdb ratings: %w", err)
+	}
+	c.logger.Info("added imdb ratings", "count", len(requests))
+	return nil
+}
+
+func (c *client) RatingsRemove(its Items) error {
+	requests := make([]GraphQLRequest, 0, len(its))
+	for _, it := range its {
+		requests = append(requests, GraphQLRequest{
+			Query:     mutationDeleteRating,
+			Variables: map[string]any{"const": it.ID},
+		})
+	}
+	if err := c.doGraphQLRequests(requests); err != nil {
+		return fmt.Errorf("failure removing imdb ratings: %w", err)
+	}
+	c.logger.Info("removed imdb ratings", "count", len(requests))
+	return nil
+}
+
+// doGraphQLRequests sends the requests one by one from a single imdb tab and
+// stops at the first request that imdb rejects.
+func (c *client) doGraphQLRequests(requests []GraphQLRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	tab, err := c.navigateAndValidateResponse(c.baseURL)
+	if err != nil {
+		return fmt.Errorf("failure navigating and validating response: %w", err)
+	}
+	for _, request := range requests {
+		body, err := json.Marshal(request)
+		if err != nil {
+			return fmt.Errorf("failure marshaling graphql request: %w", err)
+		}
+		result, err := tab.Eval(scriptGraphQL, pathGraphQL, string(body))
+		if err != nil {
+			return fmt.Errorf("failure sending graphql request: %w", err)
+		}
+		if status := result.Value.Get("status").Int(); status != http.StatusOK {
+			return fmt.Errorf("unexpected status code %d from imdb graphql api for variables %v", status, request.Variables)
+		}
+		var resp GraphQLResponse
+		if err = json.Unmarshal([]byte(result.Value.Get("body").Str()), &resp); err != nil {
+			return fmt.Errorf("failure unmarshalling graphql response: %w", err)
+		}
+		if len(resp.Errors) > 0 {
+			return fmt.Errorf("imdb graphql api rejected request with variables %v: %s", request.Variables, resp.Errors[0].Message)
+		}
+	}
+	return nil
+}
+
 func (c *client) ratingsDownload(resource *rod.Element) (Items, error) {
 	downloadButton, err := resource.Element("button[data-testid='export-status-button']")
 	if err != nil {
@@ -696,58 +854,15 @@ func isRatingsHyperlink(href, userID string) bool {
 }
 
 func isPeopleList(header []string) bool {
-	return slices.Equal(header, []string{
-		"Position",
-		"Const",
-		"Created",
-		"Modified",
-		"Description",
-		"Name",
-		"Known For",
-		"Birth Date",
-	})
+	return slices.Equal(header, peopleListHeader)
 }
 
 func isTitlesList(header []string) bool {
-	return slices.Equal(header, []string{
-		"Position",
-		"Const",
-		"Created",
-		"Modified",
-		"Description",
-		"Title",
-		"Original Title",
-		"URL",
-		"Title Type",
-		"IMDb Rating",
-		"Runtime (mins)",
-		"Year",
-		"Genres",
-		"Num Votes",
-		"Release Date",
-		"Directors",
-		"Your Rating",
-		"Date Rated",
-	})
+	return slices.Equal(header, titlesListHeader)
 }
 
 func isRatingsList(header []string) bool {
-	return slices.Equal(header, []string{
-		"Const",
-		"Your Rating",
-		"Date Rated",
-		"Title",
-		"Original Title",
-		"URL",
-		"Title Type",
-		"IMDb Rating",
-		"Runtime (mins)",
-		"Year",
-		"Genres",
-		"Num Votes",
-		"Release Date",
-		"Directors",
-	})
+	return slices.Equal(header, ratingsListHeader)
 }
 
 func transformData(data []byte) (Items, error) {
@@ -772,10 +887,31 @@ func transformData(data []byte) (Items, error) {
 			if err != nil {
 				return nil, fmt.Errorf("failure parsing created date: %w", err)
 			}
+			year, err := parseYear(record[11])
+			if err != nil {
+				return nil, fmt.Errorf("failure parsing year: %w", err)
+			}
 			items[i] = Item{
 				ID:      record[1],
+				Title:   record[5],
+				Year:    year,
 				Kind:    record[8],
 				Created: created,
+				Details: ItemDetails{
+					Position:      record[0],
+					Modified:      record[3],
+					Description:   record[4],
+					OriginalTitle: record[6],
+					URL:           record[7],
+					IMDbRating:    record[9],
+					Runtime:       record[10],
+					Genres:        record[12],
+					NumVotes:      record[13],
+					ReleaseDate:   record[14],
+					Directors:     record[15],
+					YourRating:    record[16],
+					DateRated:     record[17],
+				},
 			}
 		}
 		return items, nil
@@ -790,11 +926,27 @@ func transformData(data []byte) (Items, error) {
 			if err != nil {
 				return nil, fmt.Errorf("failure parsing created date: %w", err)
 			}
+			year, err := parseYear(record[9])
+			if err != nil {
+				return nil, fmt.Errorf("failure parsing year: %w", err)
+			}
 			items[i] = Item{
 				ID:      record[0],
+				Title:   record[3],
+				Year:    year,
 				Kind:    record[6],
 				Created: created,
 				Rating:  &rating,
+				Details: ItemDetails{
+					OriginalTitle: record[4],
+					URL:           record[5],
+					IMDbRating:    record[7],
+					Runtime:       record[8],
+					Genres:        record[10],
+					NumVotes:      record[11],
+					ReleaseDate:   record[12],
+					Directors:     record[13],
+				},
 			}
 		}
 		return items, nil
@@ -807,8 +959,16 @@ func transformData(data []byte) (Items, error) {
 			}
 			items[i] = Item{
 				ID:      record[1],
-				Kind:    "Person",
+				Title:   record[5],
+				Kind:    itemTypePerson,
 				Created: created,
+				Details: ItemDetails{
+					Position:    record[0],
+					Modified:    record[3],
+					Description: record[4],
+					KnownFor:    record[6],
+					BirthDate:   record[7],
+				},
 			}
 		}
 		return items, nil
@@ -816,6 +976,15 @@ func transformData(data []byte) (Items, error) {
 	return nil, fmt.Errorf("unrecognized list type with header %s", header)
 }
 
+// parseYear tolerates an empty year, which imdb exports for titles that
+// haven't been released yet.
+func parseYear(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
 func idExtract(href string) (string, error) {
 	pieces := strings.Split(href, "/")
 	if len(pieces) < 3 {
@@ -0,0 +1,190 @@
+package imdb
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const fileNameRatings = "ratings.csv"
+
+var (
+	peopleListHeader = []string{
+		"Position",
+		"Const",
+		"Created",
+		"Modified",
+		"Description",
+		"Name",
+		"Known For",
+		"Birth Date",
+	}
+	titlesListHeader = []string{
+		"Position",
+		"Const",
+		"Created",
+		"Modified",
+		"Description",
+		"Title",
+		"Original Title",
+		"URL",
+		"Title Type",
+		"IMDb Rating",
+		"Runtime (mins)",
+		"Year",
+		"Genres",
+		"Num Votes",
+		"Release Date",
+		"Directors",
+		"Your Rating",
+		"Date Rated",
+	}
+	ratingsListHeader = []string{
+		"Const",
+		"Your Rating",
+		"Date Rated",
+		"Title",
+		"Original Title",
+		"URL",
+		"Title Type",
+		"IMDb Rating",
+		"Runtime (mins)",
+		"Year",
+		"Genres",
+		"Num Votes",
+		"Release Date",
+		"Directors",
+	}
+)
+
+// WriteDirectory writes lists and ratings as csv files in the format of imdb
+// exports, named the way the directory source expects them, so that the
+// written directory can be used as IMDB_DIRECTORY.
+func WriteDirectory(dir string, lists Lists, ratings Items) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failure creating directory %s: %w", dir, err)
+	}
+	for _, list := range lists {
+		name := list.ListID + fileExtensionCSV
+		switch {
+		case list.IsWatchlist:
+			name = fileNameWatchlist
+		case list.ListName != "":
+			name = fmt.Sprintf("%s %s%s", list.ListID, sanitizeFileName(list.ListName), fileExtensionCSV)
+		}
+		if err := writeCSV(filepath.Join(dir, name), listRecords(list)); err != nil {
+			return err
+		}
+	}
+	if ratings == nil {
+		return nil
+	}
+	return writeCSV(filepath.Join(dir, fileNameRatings), ratingsRecords(ratings))
+}
+
+func listRecords(list List) [][]string {
+	isPeople := len(list.ListItems) > 0
+	for _, it := range list.ListItems {
+		isPeople = isPeople && it.Kind == itemTypePerson
+	}
+	if isPeople {
+		records := [][]string{peopleListHeader}
+		for _, it := range list.ListItems {
+			records = append(records, []string{
+				it.Details.Position,
+				it.ID,
+				it.Created.Format(time.DateOnly),
+				it.Details.Modified,
+				it.Details.Description,
+				it.Title,
+				it.Details.KnownFor,
+				it.Details.BirthDate,
+			})
+		}
+		return records
+	}
+	records := [][]string{titlesListHeader}
+	for _, it := range list.ListItems {
+		records = append(records, []string{
+			it.Details.Position,
+			it.ID,
+			it.Created.Format(time.DateOnly),
+			it.Details.Modified,
+			it.Details.Description,
+			it.Title,
+			it.Details.OriginalTitle,
+			it.Details.URL,
+			it.Kind,
+			it.Details.IMDbRating,
+			it.Details.Runtime,
+			formatYear(it.Year),
+			it.Details.Genres,
+			it.Details.NumVotes,
+			it.Details.ReleaseDate,
+			it.Details.Directors,
+			it.Details.YourRating,
+			it.Details.DateRated,
+		})
+	}
+	return records
+}
+
+func ratingsRecords(items Items) [][]string {
+	records := [][]string{ratingsListHeader}
+	for _, it := range items {
+		var rating string
+		if it.Rating != nil {
+			rating = strconv.FormatFloat(*it.Rating, 'f', -1, 64)
+		}
+		records = append(records, []string{
+			it.ID,
+			rating,
+			it.Created.Format(time.DateOnly),
+			it.Title,
+			it.Details.OriginalTitle,
+			it.Details.URL,
+			it.Kind,
+			it.Details.IMDbRating,
+			it.Details.Runtime,
+			formatYear(it.Year),
+			it.Details.Genres,
+			it.Details.NumVotes,
+			it.Details.ReleaseDate,
+			it.Details.Directors,
+		})
+	}
+	return records
+}
+
+func writeCSV(path string, records [][]string) error {
+	var buf bytes.Buffer
+	if err := csv.NewWriter(&buf).WriteAll(records); err != nil {
+		return fmt.Errorf("failure encoding csv file %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failure writing csv file %s: %w", path, err)
+	}
+	return nil
+}
+
+// formatYear is the inverse of parseYear.
+func formatYear(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,230 @@
+package imdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
+)
+
+const (
+	fileExtensionCSV  = ".csv"
+	fileNameWatchlist = "watchlist.csv"
+	listIDWatchlist   = "watchlist"
+)
+
+// listFileRegex matches list exports named after the imdb list id, optionally
+// followed by the list name, e.g. "ls123456789.csv" or "ls123456789 Favourites.csv".
+var listFileRegex = regexp.MustCompile(`^(ls[0-9]{9,10})(?:[ _-]+(.+))?\.csv$`)
+
+// directoryClient reads imdb exports that were downloaded manually into a
+// local directory, instead of driving a browser to export and download them.
+type directoryClient struct {
+	*config.IMDb
+	logger        *slog.Logger
+	ratingsPath   string
+	watchlistPath string
+	lists         map[string]listFile
+}
+
+type listFile struct {
+	name string
+	path string
+}
+
+func newDirectoryClient(conf *config.IMDb, logger *slog.Logger) (API, error) {
+	c := &directoryClient{
+		IMDb:   conf,
+		logger: logger,
+		lists:  make(map[string]listFile),
+	}
+	if err := c.hydrate(); err != nil {
+		return nil, fmt.Errorf("failure hydrating client: %w", err)
+	}
+	return c, nil
+}
+
+func (c *directoryClient) hydrate() error {
+	entries, err := os.ReadDir(*c.Directory)
+	if err != nil {
+		return fmt.Errorf("failure reading imdb exports directory %s: %w", *c.Directory, err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(name), fileExtensionCSV) {
+			continue
+		}
+		path := filepath.Join(*c.Directory, name)
+		if strings.EqualFold(name, fileNameWatchlist) {
+			c.watchlistPath = path
+			continue
+		}
+		if matches := listFileRegex.FindStringSubmatch(name); matches != nil {
+			lf := listFile{
+				name: matches[2],
+				path: path,
+			}
+			if lf.name == "" {
+				lf.name = matches[1]
+			}
+			c.lists[matches[1]] = lf
+			continue
+		}
+		header, err := readHeader(path)
+		if err != nil {
+			return err
+		}
+		if isRatingsList(header) {
+			c.ratingsPath = path
+			continue
+		}
+		c.logger.Warn("skipping unrecognized file in imdb exports directory", "path", path)
+	}
+	lids := slices.Clone(*c.Lists)
+	if len(lids) == 0 {
+		for lid := range c.lists {
+			lids = append(lids, lid)
+		}
+		slices.Sort(lids)
+	}
+	c.Lists = &lids
+	c.logger.Info("hydrated imdb client", "directory", *c.Directory, "ratings", c.ratingsPath, "watchlist", c.watchlistPath, "lists", lids)
+	return nil
+}
+
+// ListsExport is a no-op, since the exports are already on disk.
+func (c *directoryClient) ListsExport(_ ...string) error {
+	return nil
+}
+
+func (c *directoryClient) ListsGet(ids ...string) (Lists, error) {
+	lists := make(Lists, 0, len(ids))
+	for _, id := range ids {
+		if slices.Contains(*c.IgnoredLists, id) {
+			continue
+		}
+		lf, ok := c.lists[id]
+		if !ok {
+			return nil, fmt.Errorf("no export of imdb list %s found in directory %s", id, *c.Directory)
+		}
+		items, err := readItems(lf.path)
+		if err != nil {
+			return nil, fmt.Errorf("failure reading list %s: %w", id, err)
+		}
+		c.logger.Info("read imdb list", "count", len(items), "id", id, "name", lf.name)
+		lists = append(lists, List{
+			ListID:    id,
+			ListName:  lf.name,
+			ListItems: items,
+		})
+	}
+	return lists, nil
+}
+
+// WatchlistExport is a no-op, since the exports are already on disk.
+func (c *directoryClient) WatchlistExport() error {
+	return nil
+}
+
+func (c *directoryClient) WatchlistGet() (*List, error) {
+	watchlist := &List{
+		ListID:      listIDWatchlist,
+		ListName:    "watchlist",
+		ListItems:   make(Items, 0),
+		IsWatchlist: true,
+	}
+	if c.watchlistPath == "" {
+		c.logger.Warn("no imdb watchlist export found", "directory", *c.Directory)
+		return watchlist, nil
+	}
+	items, err := readItems(c.watchlistPath)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading watchlist: %w", err)
+	}
+	c.logger.Info("read imdb watchlist", "count", len(items))
+	watchlist.ListItems = items
+	return watchlist, nil
+}
+
+// RatingsExport is a no-op, since the exports are already on disk.
+func (c *directoryClient) RatingsExport() error {
+	return nil
+}
+
+func (c *directoryClient) RatingsGet() (Items, error) {
+	if c.ratingsPath == "" {
+		c.logger.Warn("no imdb ratings export found", "directory", *c.Directory)
+		return nil, nil
+	}
+	items, err := readItems(c.ratingsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading ratings: %w", err)
+	}
+	c.logger.Info("read imdb ratings", "count", len(items))
+	return items, nil
+}
+
+// EpisodesGet doesn't look the episodes up, since the exports don't tell which
+// show an episode belongs to, and the directory source never goes online.
+func (c *directoryClient) EpisodesGet(_ context.Context, _ ...string) (Episodes, error) {
+	return nil, NewOfflineSourceError(string(*c.Source))
+}
+
+func (c *directoryClient) ListItemsAdd(_ string, _ Items) error {
+	return NewReadOnlySourceError(string(*c.Source))
+}
+
+func (c *directoryClient) ListItemsRemove(_ string, _ Items) error {
+	return NewReadOnlySourceError(string(*c.Source))
+}
+
+func (c *directoryClient) RatingsAdd(_ Items) error {
+	return NewReadOnlySourceError(string(*c.Source))
+}
+
+func (c *directoryClient) RatingsRemove(_ Items) error {
+	return NewReadOnlySourceError(string(*c.Source))
+}
+
+func (c *directoryClient) WatchlistItemsAdd(_ Items) error {
+	return NewReadOnlySourceError(string(*c.Source))
+}
+
+func (c *directoryClient) WatchlistItemsRemove(_ Items) error {
+	return NewReadOnlySourceError(string(*c.Source))
+}
+
+func readItems(path string) (Items, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading file %s: %w", path, err)
+	}
+	items, err := transformData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failure transforming data of file %s: %w", path, err)
+	}
+	return items, nil
+}
+
+func readHeader(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading file %s: %w", path, err)
+	}
+	csvReader := csv.NewReader(bytes.NewReader(data))
+	csvReader.LazyQuotes = true
+	csvReader.FieldsPerRecord = -1
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failure reading csv header of file %s: %w", path, err)
+	}
+	return header, nil
+}
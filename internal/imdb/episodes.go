@@ -0,0 +1,100 @@
+package imdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	// episodesChunkSize bounds how many titles are looked up in a single graphql request.
+	episodesChunkSize = 100
+
+	queryEpisodes = `query Episodes($ids: [ID!]!) { titles(ids: $ids) { id series { series { id } episodeNumber { seasonNumber episodeNumber } } } }`
+)
+
+// graphQLClient queries title data, which imdb serves without a session.
+var graphQLClient = &http.Client{
+	Timeout: time.Minute,
+}
+
+// Episode tells which show an episode belongs to and where, which is how
+// other providers match episodes whose imdb id they don't know.
+type Episode struct {
+	ID       string
+	SeriesID string
+	Season   int
+	Number   int
+}
+
+type Episodes []Episode
+
+type episodesResponse struct {
+	Data struct {
+		Titles []struct {
+			ID     string `json:"id"`
+			Series *struct {
+				Series struct {
+					ID string `json:"id"`
+				} `json:"series"`
+				EpisodeNumber *struct {
+					SeasonNumber  int `json:"seasonNumber"`
+					EpisodeNumber int `json:"episodeNumber"`
+				} `json:"episodeNumber"`
+			} `json:"series"`
+		} `json:"titles"`
+	} `json:"data"`
+	Errors []GraphQLError `json:"errors"`
+}
+
+// episodesGet looks up the show and numbers of the episodes with the given ids.
+// Titles that aren't episodes, or whose numbers imdb doesn't know, are left out.
+func episodesGet(ctx context.Context, ids ...string) (Episodes, error) {
+	episodes := make(Episodes, 0, len(ids))
+	for chunk := range slices.Chunk(ids, episodesChunkSize) {
+		body, err := json.Marshal(GraphQLRequest{
+			Query:     queryEpisodes,
+			Variables: map[string]any{"ids": chunk},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failure marshaling graphql request: %w", err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, pathGraphQL, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failure creating graphql request: %w", err)
+		}
+		req.Header.Set("content-type", "application/json")
+		resp, err := graphQLClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failure sending graphql request: %w", err)
+		}
+		var r episodesResponse
+		err = json.NewDecoder(resp.Body).Decode(&r)
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d from imdb graphql api", resp.StatusCode)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failure unmarshalling graphql response: %w", err)
+		}
+		if len(r.Errors) > 0 {
+			return nil, fmt.Errorf("imdb graphql api rejected episodes request: %s", r.Errors[0].Message)
+		}
+		for _, title := range r.Data.Titles {
+			if title.Series == nil || title.Series.EpisodeNumber == nil {
+				continue
+			}
+			episodes = append(episodes, Episode{
+				ID:       title.ID,
+				SeriesID: title.Series.Series.ID,
+				Season:   title.Series.EpisodeNumber.SeasonNumber,
+				Number:   title.Series.EpisodeNumber.EpisodeNumber,
+			})
+		}
+	}
+	return episodes, nil
+}
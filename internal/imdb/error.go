@@ -15,3 +15,31 @@ func NewUnexportableResourceError(url string) error {
 		URL: url,
 	}
 }
+
+type ReadOnlySourceError struct {
+	Source string
+}
+
+func (e *ReadOnlySourceError) Error() string {
+	return fmt.Sprintf("imdb source %s is read-only", e.Source)
+}
+
+func NewReadOnlySourceError(source string) error {
+	return &ReadOnlySourceError{
+		Source: source,
+	}
+}
+
+type OfflineSourceError struct {
+	Source string
+}
+
+func (e *OfflineSourceError) Error() string {
+	return fmt.Sprintf("imdb source %s works offline", e.Source)
+}
+
+func NewOfflineSourceError(source string) error {
+	return &OfflineSourceError{
+		Source: source,
+	}
+}
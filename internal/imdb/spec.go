@@ -3,69 +3,127 @@ package imdb
 import (
 	"time"
 
-	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
 )
 
 const (
-	itemTypeMovie        = "Movie"
-	itemTypeTvEpisode    = "TV Episode"
-	itemTypeTvMiniSeries = "TV Mini Series"
-	itemTypeTvSeries     = "TV Series"
-	itemTypePerson       = "Person"
+	itemTypeMovie          = "Movie"
+	itemTypePerson         = "Person"
+	itemTypePodcastEpisode = "Podcast Episode"
+	itemTypePodcastSeries  = "Podcast Series"
+	itemTypeTvEpisode      = "TV Episode"
+	itemTypeTvMiniSeries   = "TV Mini Series"
+	itemTypeTvSeries       = "TV Series"
+	itemTypeVideoGame      = "Video Game"
 )
 
 type Item struct {
-	ID      string
-	Kind    string
-	Created time.Time
-	Rating  *float64
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	Year    int       `json:"year,omitempty"`
+	Kind    string    `json:"type"`
+	Created time.Time `json:"created"`
+	Rating  *float64  `json:"rating,omitempty"`
+	// Type overrides the type that the title type of the item maps onto, as
+	// configured by SYNC_TYPEMAPPING.
+	Type media.Type `json:"-"`
+	// Details holds the remaining columns of the imdb exports, which aren't
+	// needed to sync, but are kept when exporting imdb data.
+	Details ItemDetails `json:"details"`
 }
 
-func (it *Item) ToTraktItem() trakt.Item {
-	ti := trakt.Item{
-		Created: it.Created,
-	}
-	tiSpec := trakt.ItemSpec{
-		IDMeta: trakt.IDMeta{
+type ItemDetails struct {
+	Position      string `json:"position,omitempty"`
+	Modified      string `json:"modified,omitempty"`
+	Description   string `json:"description,omitempty"`
+	OriginalTitle string `json:"original_title,omitempty"`
+	URL           string `json:"url,omitempty"`
+	IMDbRating    string `json:"imdb_rating,omitempty"`
+	Runtime       string `json:"runtime,omitempty"`
+	Genres        string `json:"genres,omitempty"`
+	NumVotes      string `json:"num_votes,omitempty"`
+	ReleaseDate   string `json:"release_date,omitempty"`
+	Directors     string `json:"directors,omitempty"`
+	YourRating    string `json:"your_rating,omitempty"`
+	DateRated     string `json:"date_rated,omitempty"`
+	KnownFor      string `json:"known_for,omitempty"`
+	BirthDate     string `json:"birth_date,omitempty"`
+}
+
+// ToMedia converts the item to the provider-neutral model. Imdb only tells when
+// an item was added, which stands in for the rating and watch dates of rated items.
+func (it Item) ToMedia() media.Item {
+	mi := media.Item{
+		IDs: media.IDs{
 			IMDb: it.ID,
 		},
+		Title:     it.Title,
+		Year:      it.Year,
+		CreatedAt: it.Created,
 	}
 	if it.Rating != nil {
-		ratedAt := it.Created.UTC().String()
-		tiSpec.RatedAt = &ratedAt
-		tiSpec.WatchedAt = &ratedAt
-		tiSpec.Rating = it.Rating
+		rating, ratedAt := *it.Rating, it.Created.UTC()
+		mi.Rating, mi.RatedAt, mi.WatchedAt = &rating, &ratedAt, &ratedAt
+	}
+	mi.Type = it.MediaType()
+	return mi
+}
+
+// MediaType maps the imdb title type of the item onto the types the other
+// providers know, unless its type was set explicitly. Titles that have no
+// counterpart, such as video games and podcasts, are skipped. Every other
+// title type, including unknown ones, is taken for a movie.
+func (it Item) MediaType() media.Type {
+	if it.Type != "" {
+		return it.Type
 	}
 	switch it.Kind {
-	case itemTypeMovie:
-		ti.Type = trakt.ItemTypeMovie
-		ti.Movie = tiSpec
-	case itemTypeTvSeries:
-		ti.Type = trakt.ItemTypeShow
-		ti.Show = tiSpec
-	case itemTypeTvMiniSeries:
-		ti.Type = trakt.ItemTypeShow
-		ti.Show = tiSpec
+	case itemTypeTvSeries, itemTypeTvMiniSeries:
+		return media.TypeShow
 	case itemTypeTvEpisode:
-		ti.Type = trakt.ItemTypeEpisode
-		ti.Episode = tiSpec
+		return media.TypeEpisode
 	case itemTypePerson:
-		ti.Type = trakt.ItemTypePerson
-		ti.Person = tiSpec
+		return media.TypePerson
+	case itemTypePodcastSeries, itemTypePodcastEpisode, itemTypeVideoGame:
+		return media.TypeSkip
 	default:
-		ti.Type = trakt.ItemTypeMovie
-		ti.Movie = tiSpec
+		return media.TypeMovie
 	}
-	return ti
+}
+
+// NewItemFromMedia is the inverse of ToMedia, used when writing data of other
+// providers to imdb. Items without an imdb id can't be written to imdb.
+func NewItemFromMedia(mi media.Item) (*Item, bool) {
+	if mi.IDs.IMDb == "" {
+		return nil, false
+	}
+	it := Item{
+		ID:      mi.IDs.IMDb,
+		Title:   mi.Title,
+		Year:    mi.Year,
+		Created: mi.CreatedAt,
+		Rating:  mi.Rating,
+	}
+	switch mi.Type {
+	case media.TypeMovie:
+		it.Kind = itemTypeMovie
+	case media.TypeShow:
+		it.Kind = itemTypeTvSeries
+	case media.TypeEpisode:
+		it.Kind = itemTypeTvEpisode
+	case media.TypePerson:
+		it.Kind = itemTypePerson
+	}
+	return &it, true
 }
 
 type Items []Item
 
 type List struct {
-	ListID      string
-	ListName    string
-	ListItems   []Item
-	IsWatchlist bool
+	ListID      string `json:"id"`
+	ListName    string `json:"name"`
+	ListItems   []Item `json:"items"`
+	IsWatchlist bool   `json:"is_watchlist,omitempty"`
 }
 
 type Lists []List
@@ -82,6 +140,19 @@ type PageProps struct {
 	AboveTheFoldData AboveTheFoldData `json:"aboveTheFoldData"`
 }
 
+type GraphQLRequest struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+type GraphQLResponse struct {
+	Errors []GraphQLError `json:"errors"`
+}
+
+type GraphQLError struct {
+	Message string `json:"message"`
+}
+
 type AboveTheFoldData struct {
 	AuthorID        string `json:"authorId"`
 	AuthorProfileID string `json:"authorProfileId"`
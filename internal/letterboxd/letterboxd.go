@@ -0,0 +1,98 @@
+package letterboxd
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	fileNameRatings   = "ratings.csv"
+	fileNameWatchlist = "watchlist.csv"
+)
+
+var unsafeFileNameRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Film is a row of the csv files letterboxd imports. Letterboxd matches films
+// by their imdb id, falling back to the title and year.
+type Film struct {
+	IMDbID      string
+	Title       string
+	Year        int
+	Rating      float64
+	WatchedDate string
+}
+
+type Films []Film
+
+var (
+	filmsHeader   = []string{"imdbID", "Title", "Year"}
+	ratingsHeader = []string{"imdbID", "Title", "Year", "Rating", "WatchedDate"}
+)
+
+// RatingFromIMDb maps an imdb rating on the 1-10 scale onto the 0.5-5 scale of
+// letterboxd, which rates in half stars.
+func RatingFromIMDb(rating float64) float64 {
+	return min(max(rating, 1), 10) / 2
+}
+
+// FileName names the import file of ratings, the watchlist or a named list.
+func FileName(ratings, watchlist bool, listName string) string {
+	switch {
+	case ratings:
+		return fileNameRatings
+	case watchlist:
+		return fileNameWatchlist
+	}
+	name := strings.Trim(unsafeFileNameRegex.ReplaceAllString(listName, "-"), "-")
+	if name == "" {
+		name = "unnamed"
+	}
+	return "list-" + name + ".csv"
+}
+
+// WriteFilms writes films in the format letterboxd imports watchlists and lists in.
+func WriteFilms(path string, films Films) error {
+	records := [][]string{filmsHeader}
+	for _, film := range films {
+		records = append(records, []string{film.IMDbID, film.Title, formatYear(film.Year)})
+	}
+	return writeCSV(path, records)
+}
+
+// WriteRatings writes films in the format letterboxd imports ratings in.
+func WriteRatings(path string, films Films) error {
+	records := [][]string{ratingsHeader}
+	for _, film := range films {
+		records = append(records, []string{
+			film.IMDbID,
+			film.Title,
+			formatYear(film.Year),
+			strconv.FormatFloat(film.Rating, 'f', -1, 64),
+			film.WatchedDate,
+		})
+	}
+	return writeCSV(path, records)
+}
+
+func writeCSV(path string, records [][]string) error {
+	var buf bytes.Buffer
+	if err := csv.NewWriter(&buf).WriteAll(records); err != nil {
+		return fmt.Errorf("failure encoding csv file %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failure writing csv file %s: %w", path, err)
+	}
+	return nil
+}
+
+func formatYear(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
@@ -0,0 +1,44 @@
+package letterboxd
+
+import "testing"
+
+func TestRatingFromIMDb(t *testing.T) {
+	tests := []struct {
+		name   string
+		rating float64
+		want   float64
+	}{
+		{
+			name:   "lowest imdb rating",
+			rating: 1,
+			want:   0.5,
+		},
+		{
+			name:   "odd imdb rating maps onto a half star",
+			rating: 7,
+			want:   3.5,
+		},
+		{
+			name:   "highest imdb rating",
+			rating: 10,
+			want:   5,
+		},
+		{
+			name:   "below the scale is clamped",
+			rating: 0,
+			want:   0.5,
+		},
+		{
+			name:   "above the scale is clamped",
+			rating: 11,
+			want:   5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RatingFromIMDb(tt.rating); got != tt.want {
+				t.Errorf("RatingFromIMDb(%v) = %v, want %v", tt.rating, got, tt.want)
+			}
+		})
+	}
+}
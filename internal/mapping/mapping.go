@@ -0,0 +1,109 @@
+// Package mapping matches imdb ids onto the ids of other providers, for the
+// titles that a provider can't match by their imdb id.
+package mapping
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/v2"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+)
+
+const ignore = "ignore"
+
+// Mapping holds the type and the trakt ids of an imdb id. Zero values are
+// left as they are on the item the mapping is applied to. Ignored items are
+// left out of the sync altogether.
+type Mapping struct {
+	Type   media.Type `koanf:"type" json:"type,omitempty"`
+	Trakt  int        `koanf:"trakt" json:"trakt,omitempty"`
+	TMDb   int        `koanf:"tmdb" json:"tmdb,omitempty"`
+	TVDb   int        `koanf:"tvdb" json:"tvdb,omitempty"`
+	Slug   string     `koanf:"slug" json:"slug,omitempty"`
+	Ignore bool       `koanf:"ignore" json:"ignore,omitempty"`
+}
+
+// Mappings are keyed by imdb id.
+type Mappings map[string]Mapping
+
+// Load reads the yaml file maintained by the user, which maps imdb ids onto
+// their type and trakt ids, or marks them as ignored:
+//
+//	tt0000001:
+//	  type: show
+//	  tmdb: 1234
+//	tt0000002: ignore
+//
+// An empty path disables the mappings.
+func Load(path string) (Mappings, error) {
+	mappings := make(Mappings)
+	if path == "" {
+		return mappings, nil
+	}
+	k := koanf.New("/")
+	if err := k.Load(file.Provider(path), yaml.Parser()); err != nil {
+		return nil, fmt.Errorf("failure loading mapping file %s: %w", path, err)
+	}
+	for id, value := range k.Raw() {
+		if s, ok := value.(string); ok {
+			if s != ignore {
+				return nil, fmt.Errorf("mapping of %s must be either %s or a map of ids", id, ignore)
+			}
+			mappings[id] = Mapping{Ignore: true}
+			continue
+		}
+		var m Mapping
+		if err := k.Unmarshal(id, &m); err != nil {
+			return nil, fmt.Errorf("failure unmarshalling mapping of %s: %w", id, err)
+		}
+		if m.Type != "" && !slices.Contains(validTypes(), string(m.Type)) {
+			return nil, fmt.Errorf("mapping of %s has type %s, expected one of: %s", id, m.Type, strings.Join(validTypes(), ", "))
+		}
+		mappings[id] = m
+	}
+	return mappings, nil
+}
+
+func validTypes() []string {
+	return []string{
+		string(media.TypeEpisode),
+		string(media.TypeMovie),
+		string(media.TypePerson),
+		string(media.TypeShow),
+	}
+}
+
+// FromMedia returns the mapping that matches it on its type and trakt ids.
+func FromMedia(it media.Item) Mapping {
+	return Mapping{
+		Type:  it.Type,
+		Trakt: it.IDs.Trakt,
+		TMDb:  it.IDs.TMDb,
+		TVDb:  it.IDs.TVDb,
+		Slug:  it.IDs.Slug,
+	}
+}
+
+// Apply sets the type and the ids of the mapping on it, keeping its imdb id.
+func (m Mapping) Apply(it *media.Item) {
+	if m.Type != "" {
+		it.Type = m.Type
+	}
+	if m.Trakt != 0 {
+		it.IDs.Trakt = m.Trakt
+	}
+	if m.TMDb != 0 {
+		it.IDs.TMDb = m.TMDb
+	}
+	if m.TVDb != 0 {
+		it.IDs.TVDb = m.TVDb
+	}
+	if m.Slug != "" {
+		it.IDs.Slug = m.Slug
+	}
+}
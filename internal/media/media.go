@@ -0,0 +1,65 @@
+// Package media holds the provider-neutral model that the items of every
+// provider are converted to, so that any two providers can be diffed and
+// synced with each other.
+package media
+
+import (
+	"time"
+)
+
+const (
+	TypeEpisode Type = "episode"
+	TypeMovie   Type = "movie"
+	TypePerson  Type = "person"
+	TypeSeason  Type = "season"
+	TypeShow    Type = "show"
+	// TypeSkip marks titles that the other providers have no counterpart for,
+	// such as video games and podcasts, which are never synced.
+	TypeSkip Type = "skip"
+)
+
+type Type string
+
+// IDs holds the identifiers of an item across providers. Items are matched on
+// their imdb id, the others are kept so that no provider has to look them up again.
+type IDs struct {
+	IMDb  string `json:"imdb,omitempty"`
+	TMDb  int    `json:"tmdb,omitempty"`
+	TVDb  int    `json:"tvdb,omitempty"`
+	Trakt int    `json:"trakt,omitempty"`
+	Slug  string `json:"slug,omitempty"`
+}
+
+type Item struct {
+	Type      Type       `json:"type"`
+	IDs       IDs        `json:"ids"`
+	Title     string     `json:"title,omitempty"`
+	Year      int        `json:"year,omitempty"`
+	Rating    *float64   `json:"rating,omitempty"`
+	RatedAt   *time.Time `json:"rated_at,omitempty"`
+	WatchedAt *time.Time `json:"watched_at,omitempty"`
+	// Show identifies the show of a season or an episode, while Season and
+	// Episode hold their numbers. That is how episodes are matched when their
+	// imdb id is unknown to a provider, and how seasons are identified at all.
+	Show    *IDs `json:"show,omitempty"`
+	Season  int  `json:"season,omitempty"`
+	Episode int  `json:"episode,omitempty"`
+	// CreatedAt is when the item was added to its provider, which orders the
+	// changes made to a target the same way as on the source.
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	// PlayID identifies a single play of the item in the history of a provider.
+	PlayID int64 `json:"play_id,omitempty"`
+}
+
+type Items []Item
+
+// ByID keys items by their imdb id, leaving out items that have none.
+func ByID[T interface{ ToMedia() Item }](items []T) map[string]Item {
+	byID := make(map[string]Item, len(items))
+	for _, item := range items {
+		if it := item.ToMedia(); it.IDs.IMDb != "" {
+			byID[it.IDs.IMDb] = it
+		}
+	}
+	return byID
+}
@@ -0,0 +1,77 @@
+// Package rating converts ratings between the scales of imdb and trakt, for
+// users who rate harsher or milder on one of them.
+package rating
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+)
+
+// Transform converts imdb ratings to trakt ratings and back. A rating found in
+// the table is converted as the table says, any other rating is shifted by the
+// offset. The result is clamped and rounded to a whole rating, since trakt and
+// imdb only know whole ratings.
+type Transform struct {
+	offset   float64
+	min      float64
+	max      float64
+	table    map[float64]float64
+	rounding appconfig.SyncRatingRounding
+}
+
+func NewTransform(conf appconfig.Sync) (*Transform, error) {
+	table, err := appconfig.ParseRatingTable(*conf.RatingTable)
+	if err != nil {
+		return nil, err
+	}
+	return &Transform{
+		offset:   *conf.RatingOffset,
+		min:      *conf.RatingMin,
+		max:      *conf.RatingMax,
+		table:    table,
+		rounding: *conf.RatingRounding,
+	}, nil
+}
+
+// ToTrakt converts an imdb rating to the trakt scale.
+func (t *Transform) ToTrakt(rating float64) float64 {
+	converted, ok := t.table[rating]
+	if !ok {
+		converted = rating + t.offset
+	}
+	return t.round(min(max(converted, t.min), t.max))
+}
+
+// ToIMDb is the inverse of ToTrakt. It picks the imdb rating that converts to
+// the given trakt rating, and is closest to the unshifted rating when several
+// do, so that converting back and forth is stable. Trakt ratings that no imdb
+// rating converts to are only shifted back.
+func (t *Transform) ToIMDb(rating float64) float64 {
+	unshifted := rating - t.offset
+	candidates := make([]float64, 0)
+	for imdbRating := 1.0; imdbRating <= 10; imdbRating++ {
+		if t.ToTrakt(imdbRating) == rating {
+			candidates = append(candidates, imdbRating)
+		}
+	}
+	if len(candidates) == 0 {
+		return t.round(min(max(unshifted, 1), 10))
+	}
+	return slices.MinFunc(candidates, func(a, b float64) int {
+		return cmp.Or(cmp.Compare(math.Abs(a-unshifted), math.Abs(b-unshifted)), cmp.Compare(a, b))
+	})
+}
+
+func (t *Transform) round(rating float64) float64 {
+	switch t.rounding {
+	case appconfig.SyncRatingRoundingUp:
+		return math.Ceil(rating)
+	case appconfig.SyncRatingRoundingDown:
+		return math.Floor(rating)
+	default:
+		return math.Round(rating)
+	}
+}
@@ -0,0 +1,119 @@
+package rating
+
+import (
+	"testing"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+)
+
+func TestTransform_ToTrakt(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transform
+		rating    float64
+		want      float64
+	}{
+		{
+			name:      "identity",
+			transform: Transform{min: 1, max: 10},
+			rating:    7,
+			want:      7,
+		},
+		{
+			name:      "positive offset",
+			transform: Transform{offset: 1, min: 1, max: 10},
+			rating:    7,
+			want:      8,
+		},
+		{
+			name:      "clamped to max",
+			transform: Transform{offset: 2, min: 1, max: 10},
+			rating:    9,
+			want:      10,
+		},
+		{
+			name:      "clamped to min",
+			transform: Transform{offset: -2, min: 1, max: 10},
+			rating:    2,
+			want:      1,
+		},
+		{
+			name:      "table takes precedence over offset",
+			transform: Transform{offset: 1, min: 1, max: 10, table: map[float64]float64{10: 9}},
+			rating:    10,
+			want:      9,
+		},
+		{
+			name:      "rounded to nearest",
+			transform: Transform{offset: 0.5, min: 1, max: 10, rounding: appconfig.SyncRatingRoundingNearest},
+			rating:    6,
+			want:      7,
+		},
+		{
+			name:      "rounded down",
+			transform: Transform{offset: 0.5, min: 1, max: 10, rounding: appconfig.SyncRatingRoundingDown},
+			rating:    6,
+			want:      6,
+		},
+		{
+			name:      "rounded up",
+			transform: Transform{offset: 0.2, min: 1, max: 10, rounding: appconfig.SyncRatingRoundingUp},
+			rating:    6,
+			want:      7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.transform.ToTrakt(tt.rating); got != tt.want {
+				t.Errorf("ToTrakt(%v) = %v, want %v", tt.rating, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransform_ToIMDb(t *testing.T) {
+	tests := []struct {
+		name      string
+		transform Transform
+		rating    float64
+		want      float64
+	}{
+		{
+			name:      "identity",
+			transform: Transform{min: 1, max: 10},
+			rating:    7,
+			want:      7,
+		},
+		{
+			name:      "inverse of offset",
+			transform: Transform{offset: 1, min: 1, max: 10},
+			rating:    8,
+			want:      7,
+		},
+		{
+			name:      "clamped ratings convert back to the closest imdb rating",
+			transform: Transform{offset: 1, min: 1, max: 10},
+			rating:    10,
+			want:      9,
+		},
+		{
+			name:      "inverse of table",
+			transform: Transform{min: 1, max: 10, table: map[float64]float64{10: 8, 8: 7}},
+			rating:    8,
+			want:      10,
+		},
+		{
+			name:      "ratings no imdb rating converts to are shifted back",
+			transform: Transform{offset: 2, min: 1, max: 10},
+			rating:    2,
+			want:      1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.transform.ToIMDb(tt.rating); got != tt.want {
+				t.Errorf("ToIMDb(%v) = %v, want %v", tt.rating, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/mapping"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+const version = 1
+
+// State is persisted between runs, so that a run can tell what changed on
+// either side since the previous one.
+type State struct {
+	Version   int       `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Snapshot  *Snapshot `json:"snapshot,omitempty"`
+	Cache     *Cache    `json:"cache,omitempty"`
+	NotFound  NotFounds `json:"not_found,omitempty"`
+	// Mappings caches the trakt ids that the imdb ids trakt could not find
+	// were resolved to, keyed by imdb id.
+	Mappings mapping.Mappings `json:"mappings,omitempty"`
+}
+
+// Snapshot records the items imdb and trakt agreed on at the end of a
+// bidirectional run. Ratings are keyed by imdb id, lists by imdb list id.
+type Snapshot struct {
+	Ratings   map[string]float64  `json:"ratings"`
+	Watchlist []string            `json:"watchlist"`
+	Lists     map[string][]string `json:"lists"`
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		Ratings:   make(map[string]float64),
+		Watchlist: make([]string, 0),
+		Lists:     make(map[string][]string),
+	}
+}
+
+// Cache holds the trakt data fetched by the previous run, so that data which
+// hasn't changed since can be reused instead of being fetched again.
+// Lists are keyed by trakt list id.
+type Cache struct {
+	TraktActivities *trakt.LastActivities `json:"trakt_activities,omitempty"`
+	TraktRatings    trakt.Items           `json:"trakt_ratings,omitempty"`
+	TraktWatchlist  *trakt.List           `json:"trakt_watchlist,omitempty"`
+	TraktLists      map[int]trakt.List    `json:"trakt_lists,omitempty"`
+}
+
+func NewCache() *Cache {
+	return &Cache{
+		TraktLists: make(map[int]trakt.List),
+	}
+}
+
+// NotFound records an item that a target could not find, so that it isn't
+// retried on every run.
+type NotFound struct {
+	Provider    string     `json:"provider"`
+	Resource    string     `json:"resource"`
+	Target      string     `json:"target"`
+	Item        media.Item `json:"item"`
+	FirstSeenAt time.Time  `json:"first_seen_at"`
+	LastSeenAt  time.Time  `json:"last_seen_at"`
+}
+
+type NotFounds []NotFound
+
+// AddNotFound records nf, keeping when the item was first found missing in
+// case it was already recorded for the same target.
+func (s *State) AddNotFound(nf NotFound) {
+	for i, other := range s.NotFound {
+		if other.Provider == nf.Provider && other.Resource == nf.Resource && other.Target == nf.Target && other.Item.IDs.IMDb == nf.Item.IDs.IMDb {
+			nf.FirstSeenAt = other.FirstSeenAt
+			s.NotFound[i] = nf
+			return
+		}
+	}
+	s.NotFound = append(s.NotFound, nf)
+}
+
+// RemoveNotFound forgets that the provider could not find the item with the
+// given imdb id, e.g. because it found it since.
+func (s *State) RemoveNotFound(provider, id string) {
+	s.NotFound = slices.DeleteFunc(s.NotFound, func(nf NotFound) bool {
+		return nf.Provider == provider && nf.Item.IDs.IMDb == id
+	})
+}
+
+// NotFoundAt returns when the provider last could not find the item with the
+// given imdb id, for any of its targets.
+func (s *State) NotFoundAt(provider, id string) (time.Time, bool) {
+	var at time.Time
+	for _, nf := range s.NotFound {
+		if nf.Provider == provider && nf.Item.IDs.IMDb == id && nf.LastSeenAt.After(at) {
+			at = nf.LastSeenAt
+		}
+	}
+	return at, !at.IsZero()
+}
+
+// Load returns an empty state when the state file does not exist yet, since
+// that is the expected state before the first run completes.
+func Load(path string) (*State, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return &State{Version: version}, nil
+		}
+		return nil, fmt.Errorf("failure reading state file %s: %w", path, err)
+	}
+	var s State
+	if err = json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("failure unmarshalling state file %s: %w", path, err)
+	}
+	if s.Version != version {
+		return nil, fmt.Errorf("unsupported state version %d, expected %d", s.Version, version)
+	}
+	return &s, nil
+}
+
+func (s *State) Save(path string) error {
+	s.Version = version
+	s.UpdatedAt = time.Now().UTC()
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failure marshalling state: %w", err)
+	}
+	if err = os.WriteFile(path, b, 0o600); err != nil {
+		return fmt.Errorf("failure writing state file %s: %w", path, err)
+	}
+	return nil
+}
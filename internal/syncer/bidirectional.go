@@ -0,0 +1,355 @@
+package syncer
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
+)
+
+const (
+	reasonAddedOnIMDb    = "added on imdb since the last sync"
+	reasonAddedOnTrakt   = "added on trakt since the last sync"
+	reasonRemovedOnIMDb  = "removed on imdb since the last sync"
+	reasonRemovedOnTrakt = "removed on trakt since the last sync"
+)
+
+// winner is the side whose version of an item both sides end up with.
+type winner int
+
+const (
+	winnerNone winner = iota
+	winnerIMDb
+	winnerTrakt
+)
+
+// merge holds the outcome of comparing both sides against the snapshot of the
+// previous sync: the changes for each side and what the next snapshot holds.
+type merge struct {
+	toIMDb  diff
+	toTrakt diff
+	ratings map[string]float64
+	members []string
+}
+
+func newMerge() merge {
+	return merge{
+		toIMDb:  newDiff(),
+		toTrakt: newDiff(),
+		ratings: make(map[string]float64),
+		members: make([]string, 0),
+	}
+}
+
+// planBidirectional plans the changes for both imdb and trakt. Comparing both
+// sides with the snapshot taken at the end of the previous sync tells apart
+// an item that was added on one side from an item that was removed on the other.
+// The next snapshot is part of the plan and gets persisted once it is applied.
+func (s *Syncer) planBidirectional(plan *Plan) {
+	base := s.state.Snapshot
+	if base == nil {
+		s.logger.Info("no previous sync state found, items missing on either side will be added to the other", "path", *s.conf.StateFile)
+		base = state.NewSnapshot()
+	}
+	plan.Snapshot = state.NewSnapshot()
+	s.planBidirectionalLists(plan, base)
+	s.planBidirectionalRatings(plan, base)
+	if *s.conf.History {
+		s.logger.Info("skipping history sync since it is only supported from imdb to trakt")
+	}
+}
+
+func (s *Syncer) planBidirectionalLists(plan *Plan, base *state.Snapshot) {
+	if !*s.conf.Watchlist {
+		s.logger.Info("skipping watchlist sync")
+	}
+	if !*s.conf.Lists {
+		s.logger.Info("skipping lists sync")
+	}
+	if !*s.conf.Watchlist && !*s.conf.Lists {
+		return
+	}
+	for _, lid := range slices.Sorted(maps.Keys(s.user.imdbLists)) {
+		imdbList := s.user.imdbLists[lid]
+		if !s.listInScope(imdbList) {
+			continue
+		}
+		traktList, ok := s.user.traktLists[lid]
+		members := base.Lists[lid]
+		if imdbList.IsWatchlist {
+			members = base.Watchlist
+		}
+		if !ok {
+			// the trakt list gets created when the plan is applied, so none of its items were removed on trakt
+			members = nil
+		}
+		m := mergeMembers(media.ByID(imdbList.ListItems), media.ByID(traktList.ListItems), members)
+		if imdbList.IsWatchlist {
+			plan.Snapshot.Watchlist = m.members
+			s.addChangeset(plan, Changeset{
+				Provider: ProviderTrakt,
+				Resource: ResourceWatchlist,
+				Add:      m.toTrakt.Add,
+				Remove:   m.toTrakt.Remove,
+				Reasons:  m.toTrakt.Reasons,
+			})
+			s.addChangeset(plan, Changeset{
+				Provider: ProviderIMDb,
+				Resource: ResourceWatchlist,
+				Add:      m.toIMDb.Add,
+				Remove:   m.toIMDb.Remove,
+				Reasons:  m.toIMDb.Reasons,
+			})
+			continue
+		}
+		plan.Snapshot.Lists[lid] = m.members
+		s.addChangeset(plan, Changeset{
+			Provider:    ProviderTrakt,
+			Resource:    ResourceList,
+			ListID:      imdbList.ListID,
+			ListName:    imdbList.ListName,
+			TraktListID: traktList.IDMeta.Trakt,
+			Add:         m.toTrakt.Add,
+			Remove:      m.toTrakt.Remove,
+			Reasons:     m.toTrakt.Reasons,
+		})
+		s.addChangeset(plan, Changeset{
+			Provider: ProviderIMDb,
+			Resource: ResourceList,
+			ListID:   imdbList.ListID,
+			ListName: imdbList.ListName,
+			Add:      m.toIMDb.Add,
+			Remove:   m.toIMDb.Remove,
+			Reasons:  m.toIMDb.Reasons,
+		})
+	}
+}
+
+func (s *Syncer) planBidirectionalRatings(plan *Plan, base *state.Snapshot) {
+	if !*s.conf.Ratings {
+		s.logger.Info("skipping ratings sync")
+		return
+	}
+	// ratings are merged on the trakt scale, which is also what the snapshot records
+	m := s.mergeRatings(convertRatings(toMedia(s.user.imdbRatings), s.ratings.ToTrakt), toMedia(s.user.traktRatings), base.Ratings)
+	s.restoreIMDbRatings(m.toIMDb.Add, m.toIMDb.Remove)
+	plan.Snapshot.Ratings = m.ratings
+	s.addChangeset(plan, Changeset{
+		Provider: ProviderTrakt,
+		Resource: ResourceRatings,
+		Add:      m.toTrakt.Add,
+		Remove:   m.toTrakt.Remove,
+		Reasons:  m.toTrakt.Reasons,
+	})
+	s.addChangeset(plan, Changeset{
+		Provider: ProviderIMDb,
+		Resource: ResourceRatings,
+		Add:      m.toIMDb.Add,
+		Remove:   m.toIMDb.Remove,
+		Reasons:  m.toIMDb.Reasons,
+	})
+}
+
+// mergeMembers merges list membership, which can't conflict: an item present on
+// one side only was either added there or removed from the other side, depending
+// on whether it was part of the list at the end of the previous sync.
+func mergeMembers(imdbItems, traktItems map[string]media.Item, base []string) merge {
+	m := newMerge()
+	for id, imdbItem := range imdbItems {
+		if _, found := traktItems[id]; found {
+			m.members = append(m.members, id)
+			continue
+		}
+		if slices.Contains(base, id) {
+			m.toIMDb.Remove = append(m.toIMDb.Remove, imdbItem)
+			m.toIMDb.Reasons[id] = reasonRemovedOnTrakt
+			continue
+		}
+		m.toTrakt.Add = append(m.toTrakt.Add, imdbItem)
+		m.toTrakt.Reasons[id] = reasonAddedOnIMDb
+		m.members = append(m.members, id)
+	}
+	for id, traktItem := range traktItems {
+		if _, found := imdbItems[id]; found {
+			continue
+		}
+		if slices.Contains(base, id) {
+			m.toTrakt.Remove = append(m.toTrakt.Remove, traktItem)
+			m.toTrakt.Reasons[id] = reasonRemovedOnIMDb
+			continue
+		}
+		m.toIMDb.Add = append(m.toIMDb.Add, traktItem)
+		m.toIMDb.Reasons[id] = reasonAddedOnTrakt
+		m.members = append(m.members, id)
+	}
+	slices.Sort(m.members)
+	m.toIMDb.Sort()
+	m.toTrakt.Sort()
+	return m
+}
+
+// mergeRatings merges ratings, where a conflict arises when both sides changed
+// the rating of an item since the previous sync, or when one side changed it
+// while the other removed it. Conflicts are settled by the configured policy.
+func (s *Syncer) mergeRatings(imdbItems, traktItems map[string]media.Item, base map[string]float64) merge {
+	m := newMerge()
+	ids := make(map[string]struct{}, len(imdbItems))
+	for id := range imdbItems {
+		ids[id] = struct{}{}
+	}
+	for id := range traktItems {
+		ids[id] = struct{}{}
+	}
+	for _, id := range slices.Sorted(maps.Keys(ids)) {
+		imdbItem, inIMDb := imdbItems[id]
+		traktItem, inTrakt := traktItems[id]
+		baseRating, inBase := base[id]
+		var imdbRating, traktRating float64
+		if inIMDb && imdbItem.Rating != nil {
+			imdbRating = *imdbItem.Rating
+		}
+		if inTrakt && traktItem.Rating != nil {
+			traktRating = *traktItem.Rating
+		}
+		imdbChanged := inIMDb && (!inBase || baseRating != imdbRating)
+		traktChanged := inTrakt && (!inBase || baseRating != traktRating)
+		var w winner
+		var reason string
+		switch {
+		case inIMDb && inTrakt && imdbRating == traktRating:
+			m.ratings[id] = imdbRating
+			continue
+		case inIMDb && inTrakt && imdbChanged && traktChanged,
+			inIMDb && !inTrakt && inBase && imdbChanged,
+			inTrakt && !inIMDb && inBase && traktChanged:
+			w = s.resolveConflict(imdbItem, inIMDb, traktItem, inTrakt)
+			reason = fmt.Sprintf("conflict resolved by the %s policy", *s.conf.ConflictPolicy)
+		case inIMDb && inTrakt && imdbChanged:
+			w, reason = winnerIMDb, fmt.Sprintf("rating changed on imdb from %v to %v", baseRating, imdbRating)
+		case inIMDb && inTrakt:
+			w, reason = winnerTrakt, fmt.Sprintf("rating changed on trakt from %v to %v", baseRating, traktRating)
+		case inIMDb && inBase:
+			w, reason = winnerTrakt, reasonRemovedOnTrakt
+		case inIMDb:
+			w, reason = winnerIMDb, reasonAddedOnIMDb
+		case inBase:
+			w, reason = winnerIMDb, reasonRemovedOnIMDb
+		default:
+			w, reason = winnerTrakt, reasonAddedOnTrakt
+		}
+		switch w {
+		case winnerIMDb:
+			if inIMDb {
+				m.toTrakt.Add = append(m.toTrakt.Add, imdbItem)
+				m.ratings[id] = imdbRating
+			} else {
+				m.toTrakt.Remove = append(m.toTrakt.Remove, traktItem)
+			}
+			m.toTrakt.Reasons[id] = reason
+		case winnerTrakt:
+			if inTrakt {
+				m.toIMDb.Add = append(m.toIMDb.Add, traktItem)
+				m.ratings[id] = traktRating
+			} else {
+				m.toIMDb.Remove = append(m.toIMDb.Remove, imdbItem)
+			}
+			m.toIMDb.Reasons[id] = reason
+		default:
+			s.logger.Warn("skipping conflicting rating", "imdbID", id, "imdbRating", imdbRating, "traktRating", traktRating, "inIMDb", inIMDb, "inTrakt", inTrakt)
+			// keeping the previous rating in the snapshot makes the next sync detect the conflict again
+			if inBase {
+				m.ratings[id] = baseRating
+			}
+		}
+	}
+	m.toIMDb.Sort()
+	m.toTrakt.Sort()
+	return m
+}
+
+// settleSnapshot keeps the items that cs didn't change as they were in the
+// previous snapshot, so that the next snapshot only records what both sides
+// really agree on. Those are the items left out of cs while planning, e.g. the
+// removals over the removal threshold, the items trakt could not find, and
+// every item of a changeset that wasn't applied. Otherwise the next sync would
+// take an item that never reached trakt for one that was removed on trakt.
+func settleSnapshot(next, base *state.Snapshot, cs Changeset, applied bool) {
+	if next == nil {
+		return
+	}
+	changed := make(map[string]struct{}, len(cs.Add)+len(cs.Remove))
+	if applied {
+		for _, it := range slices.Concat(cs.Add, cs.Remove) {
+			changed[it.IDs.IMDb] = struct{}{}
+		}
+		for _, it := range cs.NotFound {
+			delete(changed, it.IDs.IMDb)
+		}
+	}
+	for id := range cs.Reasons {
+		if _, ok := changed[id]; ok {
+			continue
+		}
+		switch cs.Resource {
+		case ResourceRatings:
+			if rating, ok := base.Ratings[id]; ok {
+				next.Ratings[id] = rating
+			} else {
+				delete(next.Ratings, id)
+			}
+		case ResourceWatchlist:
+			next.Watchlist = settleMember(next.Watchlist, base.Watchlist, id)
+		case ResourceList:
+			members := base.Lists[cs.ListID]
+			if cs.Provider == ProviderTrakt && cs.TraktListID == 0 {
+				// the trakt list is created by cs, which is planned without any previous members
+				members = nil
+			}
+			next.Lists[cs.ListID] = settleMember(next.Lists[cs.ListID], members, id)
+		}
+	}
+}
+
+// settleMember keeps id a member of the list if and only if it was a member in
+// the previous snapshot.
+func settleMember(members, base []string, id string) []string {
+	members = slices.DeleteFunc(members, func(member string) bool {
+		return member == id
+	})
+	if slices.Contains(base, id) {
+		members = append(members, id)
+		slices.Sort(members)
+	}
+	return members
+}
+
+// resolveConflict picks the side whose rating wins according to the conflict
+// policy. With newest-wins, a side that still has the item wins over a side that
+// removed it, since removals carry no timestamp to compare with.
+func (s *Syncer) resolveConflict(imdbItem media.Item, inIMDb bool, traktItem media.Item, inTrakt bool) winner {
+	switch *s.conf.ConflictPolicy {
+	case appconfig.SyncConflictPolicyIMDbWins:
+		return winnerIMDb
+	case appconfig.SyncConflictPolicyTraktWins:
+		return winnerTrakt
+	case appconfig.SyncConflictPolicyNewestWins:
+		if !inTrakt {
+			return winnerIMDb
+		}
+		if !inIMDb {
+			return winnerTrakt
+		}
+		if traktItem.RatedAt == nil {
+			return winnerNone
+		}
+		if imdbItem.CreatedAt.After(*traktItem.RatedAt) {
+			return winnerIMDb
+		}
+		return winnerTrakt
+	default:
+		return winnerNone
+	}
+}
@@ -0,0 +1,108 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+// fetchActivities fetches when the trakt data was last changed, which tells
+// whether the data cached by the previous run can be reused.
+func (s *Syncer) fetchActivities(ctx context.Context) error {
+	s.cache = state.NewCache()
+	if !*s.conf.Incremental {
+		return nil
+	}
+	activities, err := s.traktClient.LastActivitiesGet(ctx)
+	if err != nil {
+		return fmt.Errorf("failure fetching trakt last activities: %w", err)
+	}
+	s.cache.TraktActivities = activities
+	return nil
+}
+
+// cached reports whether the data cached by the previous run is up to date,
+// i.e. trakt reports the same last change for it as it did back then.
+func (s *Syncer) cached(updatedAt func(*trakt.LastActivities) time.Time) bool {
+	if s.state.Cache == nil || s.state.Cache.TraktActivities == nil || s.cache.TraktActivities == nil {
+		return false
+	}
+	return updatedAt(s.cache.TraktActivities).Equal(updatedAt(s.state.Cache.TraktActivities))
+}
+
+// fetchTraktLists fetches the items of the trakt lists that were updated since
+// the previous run, and reuses the cached items of the others.
+func (s *Syncer) fetchTraktLists(ctx context.Context, metas trakt.Lists) (trakt.Lists, error) {
+	lists := make(trakt.Lists, 0, len(metas))
+	ids := make(trakt.IDMetas, 0, len(metas))
+	for _, meta := range metas {
+		if list, ok := s.cachedTraktList(meta); ok {
+			lists = append(lists, list)
+			continue
+		}
+		ids = append(ids, meta.IDMeta)
+	}
+	if len(lists) > 0 {
+		s.logger.Info("reusing cached trakt lists that are unchanged since the previous run", "count", len(lists))
+	}
+	fetched, err := s.traktClient.ListsGet(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	lists = append(lists, fetched...)
+	for _, list := range lists {
+		s.cache.TraktLists[list.IDMeta.Trakt] = list
+	}
+	return lists, nil
+}
+
+func (s *Syncer) cachedTraktList(meta trakt.List) (trakt.List, bool) {
+	if !*s.conf.Incremental || s.state.Cache == nil || meta.UpdatedAt == nil {
+		return trakt.List{}, false
+	}
+	list, ok := s.state.Cache.TraktLists[meta.IDMeta.Trakt]
+	if !ok || list.UpdatedAt == nil || !list.UpdatedAt.Equal(*meta.UpdatedAt) {
+		return trakt.List{}, false
+	}
+	list.IDMeta = meta.IDMeta
+	return list, true
+}
+
+func (s *Syncer) fetchTraktWatchlist(ctx context.Context) (*trakt.List, error) {
+	if s.cached((*trakt.LastActivities).WatchlistUpdatedAt) && s.state.Cache.TraktWatchlist != nil {
+		s.logger.Info("reusing cached trakt watchlist that is unchanged since the previous run")
+		s.cache.TraktWatchlist = s.state.Cache.TraktWatchlist
+		return s.cache.TraktWatchlist, nil
+	}
+	watchlist, err := s.traktClient.WatchlistGet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.TraktWatchlist = watchlist
+	return watchlist, nil
+}
+
+func (s *Syncer) fetchTraktRatings(ctx context.Context) (trakt.Items, error) {
+	if s.cached((*trakt.LastActivities).RatingsUpdatedAt) && s.state.Cache.TraktRatings != nil {
+		s.logger.Info("reusing cached trakt ratings that are unchanged since the previous run")
+		s.cache.TraktRatings = s.state.Cache.TraktRatings
+		return s.cache.TraktRatings, nil
+	}
+	ratings, err := s.traktClient.RatingsGet(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.TraktRatings = ratings
+	return ratings, nil
+}
+
+// caches reports whether Apply persists what this run fetched from trakt, so
+// that the next run only has to fetch what changed in the meantime. Only runs
+// that fetched trakt data themselves and get to apply their plan persist it,
+// hence plans and dry runs leave the sync state as it is.
+func (s *Syncer) caches() bool {
+	return *s.conf.Incremental && s.cache != nil
+}
@@ -1,64 +1,117 @@
 package syncer
 
 import (
+	"cmp"
+	"fmt"
 	"slices"
 
-	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
-	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+)
+
+const (
+	reasonMissingInTraktHistory = "rated on imdb, but missing in trakt history"
+	reasonRewatchedOnIMDb       = "rated on imdb after the latest play in trakt history"
 )
 
 type diff struct {
-	Add    []trakt.Item
-	Remove []trakt.Item
+	Add    media.Items
+	Remove media.Items
+	// Reasons explains why each item ended up in the diff, keyed by imdb id.
+	Reasons map[string]string
 }
 
 func newDiff() diff {
 	return diff{
-		Add:    make(trakt.Items, 0),
-		Remove: make(trakt.Items, 0),
+		Add:     make(media.Items, 0),
+		Remove:  make(media.Items, 0),
+		Reasons: make(map[string]string),
 	}
 }
 
+// Sort orders the items the way they were added to their provider, so that
+// targets which keep the order end up like the source.
 func (d *diff) Sort() {
-	sortFunc := func(a, b trakt.Item) int { return a.Created.Compare(b.Created) }
+	sortFunc := func(a, b media.Item) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.IDs.IMDb, b.IDs.IMDb))
+	}
 	slices.SortFunc(d.Add, sortFunc)
 	slices.SortFunc(d.Remove, sortFunc)
 }
 
-func listDiff(imdbList imdb.List, traktList trakt.List) diff {
-	imdbItems := make(map[string]imdb.Item)
-	for _, item := range imdbList.ListItems {
-		imdbItems[item.ID] = item
-	}
-	traktItems := make(map[string]trakt.Item)
-	for _, item := range traktList.ListItems {
-		id, err := item.GetItemID()
-		if err != nil || id == nil {
-			continue
-		}
-		traktItems[*id] = item
-	}
-	return itemsDifference(imdbItems, traktItems)
-}
-
-func itemsDifference(imdbItems map[string]imdb.Item, traktItems map[string]trakt.Item) diff {
+// itemsDifference computes what has to change on the target provider for it
+// to hold the same items as the source provider. Items of the source that are
+// missing on the target or rated differently are added, while items of the
+// target that are absent on the source are removed. Any two providers can be
+// diffed, as long as their items are converted to media items first.
+func itemsDifference(source, target map[string]media.Item, from, to Provider) diff {
 	diff := newDiff()
-	for id, imdbItem := range imdbItems {
-		traktItem := imdbItem.ToTraktItem()
-		if _, found := traktItems[id]; !found {
-			diff.Add = append(diff.Add, traktItem)
+	for id, sourceItem := range source {
+		targetItem, found := target[id]
+		if !found {
+			diff.Add = append(diff.Add, sourceItem)
+			diff.Reasons[id] = fmt.Sprintf("missing on %s", to)
 			continue
 		}
-		if imdbItem.Rating != nil && *imdbItem.Rating != traktItems[id].Rating {
-			diff.Add = append(diff.Add, traktItem)
+		if sourceItem.Rating != nil && (targetItem.Rating == nil || *sourceItem.Rating != *targetItem.Rating) {
+			diff.Add = append(diff.Add, sourceItem)
+			diff.Reasons[id] = fmt.Sprintf("rating changed from %v to %v", formatRating(targetItem.Rating), *sourceItem.Rating)
 		}
 	}
-	for id, traktItem := range traktItems {
-		if _, found := imdbItems[id]; !found {
-			traktItem.Created = imdbItems[id].Created
-			diff.Remove = append(diff.Remove, traktItem)
+	for id, targetItem := range target {
+		if _, found := source[id]; !found {
+			diff.Remove = append(diff.Remove, targetItem)
+			diff.Reasons[id] = fmt.Sprintf("absent on %s", from)
 		}
 	}
 	diff.Sort()
 	return diff
 }
+
+func formatRating(rating *float64) any {
+	if rating == nil {
+		return 0
+	}
+	return *rating
+}
+
+// toMedia converts items that are keyed by imdb id to media items.
+func toMedia[T interface{ ToMedia() media.Item }](items map[string]T) map[string]media.Item {
+	mediaItems := make(map[string]media.Item, len(items))
+	for id, item := range items {
+		mediaItems[id] = item.ToMedia()
+	}
+	return mediaItems
+}
+
+// convertRatings returns copies of items with their ratings converted, which
+// puts the ratings of two providers on the same scale before they are compared.
+func convertRatings(items map[string]media.Item, convert func(float64) float64) map[string]media.Item {
+	converted := make(map[string]media.Item, len(items))
+	for id, it := range items {
+		converted[id] = convertRating(it, convert)
+	}
+	return converted
+}
+
+func convertRating(it media.Item, convert func(float64) float64) media.Item {
+	if it.Rating != nil {
+		rating := convert(*it.Rating)
+		it.Rating = &rating
+	}
+	return it
+}
+
+// restoreIMDbRatings puts the changes to imdb ratings, which were compared on
+// the trakt scale, back on the imdb scale. Added ratings are converted back,
+// while removed ratings are restored from imdb, so that undoing the removal
+// brings back the exact rating.
+func (s *Syncer) restoreIMDbRatings(add, remove media.Items) {
+	for i, it := range add {
+		add[i] = convertRating(it, s.ratings.ToIMDb)
+	}
+	for i, it := range remove {
+		if imdbItem, ok := s.user.imdbRatings[it.IDs.IMDb]; ok {
+			remove[i] = imdbItem.ToMedia()
+		}
+	}
+}
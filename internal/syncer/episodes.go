@@ -0,0 +1,88 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+// resolveEpisodes looks up the show and numbers of the imdb episodes that are
+// added to trakt, so that trakt can still match the episodes whose imdb id it
+// doesn't know. Episodes are matched by their imdb id whenever possible, hence
+// a failed lookup is not fatal.
+func (s *Syncer) resolveEpisodes(ctx context.Context, plan *Plan) {
+	if s.imdbClient == nil {
+		return
+	}
+	unresolved := make(map[string][]*media.Item)
+	for i := range plan.Changesets {
+		cs := &plan.Changesets[i]
+		if cs.Provider != ProviderTrakt {
+			continue
+		}
+		for j := range cs.Add {
+			it := &cs.Add[j]
+			if it.Type == media.TypeEpisode && it.Show == nil && it.IDs.IMDb != "" {
+				unresolved[it.IDs.IMDb] = append(unresolved[it.IDs.IMDb], it)
+			}
+		}
+	}
+	if len(unresolved) == 0 {
+		return
+	}
+	ids := make([]string, 0, len(unresolved))
+	for id := range unresolved {
+		ids = append(ids, id)
+	}
+	episodes, err := s.imdbClient.EpisodesGet(ctx, ids...)
+	var offlineErr *imdb.OfflineSourceError
+	if errors.As(err, &offlineErr) {
+		s.logger.Info("skipping the lookup of the shows of imdb episodes, since the imdb source works offline, trakt will only match them by imdb id", "count", len(ids))
+		return
+	}
+	if err != nil {
+		s.logger.Warn("failure resolving the shows of imdb episodes, trakt will only match them by imdb id", logger.Error(err))
+		return
+	}
+	for _, episode := range episodes {
+		for _, it := range unresolved[episode.ID] {
+			it.Show = &media.IDs{IMDb: episode.SeriesID}
+			it.Season, it.Episode = episode.Season, episode.Number
+		}
+		delete(unresolved, episode.ID)
+	}
+	for id := range unresolved {
+		s.logger.Warn("imdb episode has no known show and numbers, trakt will only match it by imdb id", "imdbID", id, "title", unresolved[id][0].Title)
+	}
+	s.logger.Info("resolved the shows of imdb episodes", "count", len(episodes))
+}
+
+// reportSeasons reports the seasons in the trakt ratings, watchlist or list
+// named target, which can't be synced with imdb, since imdb only knows shows
+// and episodes.
+func (s *Syncer) reportSeasons(target string, its trakt.Items) {
+	count := 0
+	for _, it := range its {
+		if it.Type != trakt.ItemTypeSeason {
+			continue
+		}
+		mi := it.ToMedia()
+		var show string
+		if mi.Show != nil {
+			show = mi.Show.IMDb
+		}
+		attrs := []any{"target", target, "showIMDbID", show, "title", it.Show.Title, "season", mi.Season}
+		if mi.Rating != nil {
+			attrs = append(attrs, "rating", *mi.Rating)
+		}
+		s.logger.Info("skipping trakt season, since imdb has no seasons", attrs...)
+		count++
+	}
+	if count > 0 {
+		s.logger.Info("skipped trakt seasons", "target", target, "count", count)
+	}
+}
@@ -0,0 +1,23 @@
+package syncer
+
+import "fmt"
+
+type RemovalThresholdExceededError struct {
+	Provider Provider
+	Target   string
+	Removals int
+	Total    int
+}
+
+func (e *RemovalThresholdExceededError) Error() string {
+	return fmt.Sprintf("removing %d of %d items from %s %s exceeds the removal threshold", e.Removals, e.Total, e.Provider, e.Target)
+}
+
+func NewRemovalThresholdExceededError(provider Provider, target string, removals, total int) error {
+	return &RemovalThresholdExceededError{
+		Provider: provider,
+		Target:   target,
+		Removals: removals,
+		Total:    total,
+	}
+}
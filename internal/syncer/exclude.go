@@ -0,0 +1,120 @@
+package syncer
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+// dropExcluded leaves out the items that are excluded from the sync on either
+// side, so that they are neither added nor removed anywhere. Items are excluded
+// by the mapping file, when their imdb title type has no counterpart on the
+// other providers, or by the imdb title type when it isn't in
+// SYNC_INCLUDEDTYPES or is in SYNC_EXCLUDEDTYPES.
+func (s *Syncer) dropExcluded() {
+	s.mapTypes()
+	excluded := make(map[string]struct{})
+	unsupported := make(map[string]int)
+	skipped := make(map[string]int)
+	for _, it := range s.imdbItems() {
+		if _, ok := excluded[it.ID]; ok {
+			continue
+		}
+		switch {
+		case s.resolver.ignored(it.ID):
+			excluded[it.ID] = struct{}{}
+		case it.MediaType() == media.TypeSkip:
+			excluded[it.ID] = struct{}{}
+			unsupported[it.Kind]++
+		case !s.includesTitleType(it.Kind):
+			excluded[it.ID] = struct{}{}
+			skipped[it.Kind]++
+		}
+	}
+	ignored := make(map[string]struct{})
+	drop := func(id string) bool {
+		if s.resolver.ignored(id) {
+			ignored[id] = struct{}{}
+			return true
+		}
+		_, ok := excluded[id]
+		return ok
+	}
+	for lid, list := range s.user.imdbLists {
+		list.ListItems = slices.DeleteFunc(slices.Clone(list.ListItems), func(it imdb.Item) bool {
+			return drop(it.ID)
+		})
+		s.user.imdbLists[lid] = list
+	}
+	for lid, list := range s.user.traktLists {
+		list.ListItems = slices.DeleteFunc(slices.Clone(list.ListItems), func(it trakt.Item) bool {
+			return drop(it.ToMedia().IDs.IMDb)
+		})
+		s.user.traktLists[lid] = list
+	}
+	maps.DeleteFunc(s.user.imdbRatings, func(id string, _ imdb.Item) bool {
+		return drop(id)
+	})
+	maps.DeleteFunc(s.user.traktRatings, func(id string, _ trakt.Item) bool {
+		return drop(id)
+	})
+	for _, id := range slices.Sorted(maps.Keys(ignored)) {
+		s.logger.Info("ignoring item as configured in the mapping file", "imdbID", id)
+	}
+	for _, kind := range slices.Sorted(maps.Keys(unsupported)) {
+		s.logger.Info("skipping imdb items of title type that isn't synced", "type", kind, "count", unsupported[kind])
+	}
+	for _, kind := range slices.Sorted(maps.Keys(skipped)) {
+		s.logger.Info("skipping imdb items of excluded title type", "type", kind, "count", skipped[kind])
+	}
+}
+
+// mapTypes sets the type of the imdb items whose title type is mapped onto
+// another type through SYNC_TYPEMAPPING.
+func (s *Syncer) mapTypes() {
+	if len(s.types) == 0 {
+		return
+	}
+	mapType := func(it *imdb.Item) {
+		if t, ok := s.types[it.Kind]; ok {
+			it.Type = media.Type(t)
+		}
+	}
+	for _, list := range s.user.imdbLists {
+		for i := range list.ListItems {
+			mapType(&list.ListItems[i])
+		}
+	}
+	for id, it := range s.user.imdbRatings {
+		mapType(&it)
+		s.user.imdbRatings[id] = it
+	}
+}
+
+// imdbItems returns every item of the imdb lists and ratings, which are the
+// only items that carry an imdb title type.
+func (s *Syncer) imdbItems() imdb.Items {
+	items := make(imdb.Items, 0, len(s.user.imdbRatings))
+	for _, list := range s.user.imdbLists {
+		items = append(items, list.ListItems...)
+	}
+	for _, it := range s.user.imdbRatings {
+		items = append(items, it)
+	}
+	return items
+}
+
+// includesTitleType reports whether items of the given imdb title type are
+// synced. Items of unknown title type always are.
+func (s *Syncer) includesTitleType(kind string) bool {
+	if kind == "" {
+		return true
+	}
+	if len(*s.conf.IncludedTypes) > 0 && !slices.Contains(*s.conf.IncludedTypes, kind) {
+		return false
+	}
+	return !slices.Contains(*s.conf.ExcludedTypes, kind)
+}
@@ -0,0 +1,94 @@
+package syncer
+
+import (
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+)
+
+// guardRemovals checks the removals of every changeset against the configured
+// thresholds, which protect against wiping a target when one side comes back
+// empty, e.g. because an imdb export could not be parsed. Exceeding a
+// threshold either fails the plan or drops the removals of that changeset.
+// Without enforce, as when planning in dry-run mode, exceeding a threshold is
+// only logged, which is why plans are checked again when they are applied.
+func (s *Syncer) guardRemovals(plan *Plan, enforce bool) error {
+	if plan.Undo {
+		// undoing a sync only reverts what the sync did
+		return nil
+	}
+	for i := range plan.Changesets {
+		cs := &plan.Changesets[i]
+		if cs.DryRun || !s.exceedsRemovalThreshold(len(cs.Remove), cs.TargetSize) {
+			continue
+		}
+		err := NewRemovalThresholdExceededError(cs.Provider, cs.target(), len(cs.Remove), cs.TargetSize)
+		switch {
+		case !enforce:
+			s.logger.Warn("sync would have exceeded the removal threshold", logger.Error(err))
+		case *s.conf.RemovalGuard == appconfig.SyncRemovalGuardAddOnly:
+			s.logger.Warn("skipping removals that exceed the removal threshold", logger.Error(err))
+			cs.Remove = cs.Remove[:0]
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Syncer) exceedsRemovalThreshold(removals, total int) bool {
+	if removals == 0 {
+		return false
+	}
+	if limit := *s.conf.MaxRemovals; limit > 0 && removals > limit {
+		return true
+	}
+	if limit := *s.conf.MaxRemovalPercent; limit > 0 && total > 0 {
+		return float64(removals)/float64(total)*100 > limit
+	}
+	return false
+}
+
+// measureTargets records the size of the target of every changeset, which
+// the removals are checked against.
+func (s *Syncer) measureTargets(plan *Plan) {
+	for i := range plan.Changesets {
+		plan.Changesets[i].TargetSize = s.targetSize(&plan.Changesets[i])
+	}
+}
+
+// targetSize returns how many items the target of cs currently holds, or 0
+// when that is unknown, as is the case for trakt history that could only be
+// fetched item by item.
+func (s *Syncer) targetSize(cs *Changeset) int {
+	switch cs.Provider {
+	case ProviderTrakt:
+		switch cs.Resource {
+		case ResourceRatings:
+			return len(s.user.traktRatings)
+		case ResourceList:
+			return len(s.user.traktLists[cs.ListID].ListItems)
+		case ResourceHistory:
+			return len(s.user.traktHistory)
+		case ResourceWatchlist:
+			for _, list := range s.user.traktLists {
+				if list.IsWatchlist {
+					return len(list.ListItems)
+				}
+			}
+		}
+	case ProviderIMDb:
+		switch cs.Resource {
+		case ResourceRatings:
+			return len(s.user.imdbRatings)
+		case ResourceList:
+			return len(s.user.imdbLists[cs.ListID].ListItems)
+		case ResourceWatchlist:
+			for _, list := range s.user.imdbLists {
+				if list.IsWatchlist {
+					return len(list.ListItems)
+				}
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package syncer
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+)
+
+func removals(n int) media.Items {
+	items := make(media.Items, n)
+	for i := range items {
+		items[i] = media.Item{Type: media.TypeMovie}
+	}
+	return items
+}
+
+func TestSyncer_guardRemovals(t *testing.T) {
+	tests := []struct {
+		name              string
+		maxRemovals       int
+		maxRemovalPercent float64
+		guard             appconfig.SyncRemovalGuard
+		undo              bool
+		enforce           bool
+		changeset         Changeset
+		wantErr           bool
+		wantRemovals      int
+	}{
+		{
+			name:              "under the thresholds",
+			maxRemovals:       10,
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(4), TargetSize: 10},
+			wantRemovals:      4,
+		},
+		{
+			name:              "over the max removals",
+			maxRemovals:       3,
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(4), TargetSize: 10},
+			wantErr:           true,
+			wantRemovals:      4,
+		},
+		{
+			name:              "over the max removal percent",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(6), TargetSize: 10},
+			wantErr:           true,
+			wantRemovals:      6,
+		},
+		{
+			name:              "wiping the whole target",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(1), TargetSize: 1},
+			wantErr:           true,
+			wantRemovals:      1,
+		},
+		{
+			name:         "disabled thresholds",
+			guard:        appconfig.SyncRemovalGuardAbort,
+			enforce:      true,
+			changeset:    Changeset{Remove: removals(10), TargetSize: 10},
+			wantRemovals: 10,
+		},
+		{
+			name:              "unknown target size only checks the max removals",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(10)},
+			wantRemovals:      10,
+		},
+		{
+			name:              "add-only drops the removals",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAddOnly,
+			enforce:           true,
+			changeset:         Changeset{Add: removals(2), Remove: removals(6), TargetSize: 10},
+			wantRemovals:      0,
+		},
+		{
+			name:              "not enforced only warns",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			changeset:         Changeset{Remove: removals(6), TargetSize: 10},
+			wantRemovals:      6,
+		},
+		{
+			name:              "dry-run changesets are not checked",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(6), TargetSize: 10, DryRun: true},
+			wantRemovals:      6,
+		},
+		{
+			name:              "undo plans are not checked",
+			maxRemovalPercent: 50,
+			guard:             appconfig.SyncRemovalGuardAbort,
+			undo:              true,
+			enforce:           true,
+			changeset:         Changeset{Remove: removals(6), TargetSize: 10},
+			wantRemovals:      6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Syncer{
+				logger: logger.NewLogger(io.Discard),
+				conf: appconfig.Sync{
+					MaxRemovals:       &tt.maxRemovals,
+					MaxRemovalPercent: &tt.maxRemovalPercent,
+					RemovalGuard:      &tt.guard,
+				},
+			}
+			cs := tt.changeset
+			cs.Provider, cs.Resource = ProviderTrakt, ResourceWatchlist
+			plan := newPlan()
+			plan.Undo = tt.undo
+			plan.add(cs)
+			err := s.guardRemovals(plan, tt.enforce)
+			var thresholdErr *RemovalThresholdExceededError
+			if tt.wantErr != errors.As(err, &thresholdErr) {
+				t.Fatalf("guardRemovals() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := len(plan.Changesets[0].Remove); got != tt.wantRemovals {
+				t.Errorf("guardRemovals() left %d removals, want %d", got, tt.wantRemovals)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+// historyGetter returns the plays of an item in trakt history.
+type historyGetter func(ctx context.Context, it media.Item) (trakt.Items, error)
+
+// traktHistory fetches the whole trakt history of the user once it is first
+// needed, which takes a request per page instead of a request per rated item,
+// and looks the plays up in memory. Should that fail, the plays are fetched
+// item by item instead.
+func (s *Syncer) traktHistory() historyGetter {
+	var plays map[string]trakt.Items
+	fallback := false
+	return func(ctx context.Context, it media.Item) (trakt.Items, error) {
+		if plays == nil && !fallback {
+			var err error
+			if plays, err = s.fetchTraktHistory(ctx); err != nil {
+				s.logger.Warn("failure fetching trakt history, falling back to fetching it item by item", logger.Error(err))
+				fallback = true
+			}
+			s.user.traktHistory = plays
+		}
+		if fallback {
+			return s.traktClient.HistoryGet(ctx, string(it.Type), it.IDs.IMDb)
+		}
+		return plays[it.IDs.IMDb], nil
+	}
+}
+
+// fetchTraktHistory returns the plays in trakt history keyed by imdb id.
+// Episode plays are keyed by the imdb id of their show as well, since shows are
+// rated as a whole and trakt only records plays of their episodes.
+func (s *Syncer) fetchTraktHistory(ctx context.Context) (map[string]trakt.Items, error) {
+	history, err := s.traktClient.HistoryGetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history = s.resolver.imdbIDs(history)
+	plays := make(map[string]trakt.Items)
+	for i := range history {
+		id, err := history[i].GetItemID()
+		if err != nil {
+			return nil, fmt.Errorf("failure fetching trakt item id: %w", err)
+		}
+		if id != nil && *id != "" {
+			plays[*id] = append(plays[*id], history[i])
+		}
+		if history[i].Type == trakt.ItemTypeEpisode && history[i].Show.IDMeta.IMDb != "" {
+			show := history[i].Show.IDMeta.IMDb
+			plays[show] = append(plays[show], history[i])
+		}
+	}
+	s.logger.Info("fetched trakt history", "plays", len(history), "items", len(plays))
+	return plays, nil
+}
+
+// watchedAt returns the date that a rated imdb item is added to trakt history
+// at, as configured by SYNC_WATCHEDAT. Unknown dates are zero, which is how
+// they are sent to trakt. Release dates fall back to the rated date, since
+// imdb doesn't know the release date of every title.
+func (s *Syncer) watchedAt(it media.Item) *time.Time {
+	switch *s.conf.WatchedAt {
+	case appconfig.SyncWatchedAtUnknown:
+		return &time.Time{}
+	case appconfig.SyncWatchedAtReleased:
+		released, err := time.Parse(time.DateOnly, s.user.imdbRatings[it.IDs.IMDb].Details.ReleaseDate)
+		if err == nil {
+			return &released
+		}
+	}
+	return it.WatchedAt
+}
+
+// rewatched reports whether an item that is already in trakt history was
+// rated on imdb after its latest play, which is taken as another play when
+// SYNC_REWATCHES is enabled. Plays at unknown dates never count as the latest.
+func (s *Syncer) rewatched(it media.Item, history trakt.Items) bool {
+	if !*s.conf.Rewatches || it.RatedAt == nil {
+		return false
+	}
+	for _, play := range history {
+		watchedAt := play.ToMedia().WatchedAt
+		if watchedAt != nil && !watchedAt.Before(*it.RatedAt) {
+			return false
+		}
+	}
+	return true
+}
+
+// lastPlayID returns the highest id of the plays in history, or 0 when there
+// are none.
+func lastPlayID(history map[string]trakt.Items) int64 {
+	var last int64
+	for _, plays := range history {
+		for _, play := range plays {
+			last = max(last, play.ID)
+		}
+	}
+	return last
+}
+
+// addedPlays returns the plays that adding items to history created: the plays
+// of the items that trakt numbered after lastPlayID, which were watched at the
+// date the item was added at. Plays at unknown dates can only be told apart by
+// their id, hence they are left alone when lastPlayID is unknown.
+func addedPlays(plays, items media.Items, lastPlayID int64) media.Items {
+	added := make(media.Items, 0)
+	for _, play := range plays {
+		if play.PlayID <= lastPlayID {
+			continue
+		}
+		if slices.ContainsFunc(items, func(it media.Item) bool {
+			if it.IDs.IMDb == "" || it.IDs.IMDb != play.IDs.IMDb && it.IDs.IMDb != showIMDbID(play) {
+				return false
+			}
+			if it.WatchedAt == nil || it.WatchedAt.IsZero() {
+				return lastPlayID != 0
+			}
+			return play.WatchedAt != nil && play.WatchedAt.Truncate(time.Second).Equal(it.WatchedAt.Truncate(time.Second))
+		}) {
+			added = append(added, play)
+		}
+	}
+	return added
+}
+
+// traktPlays fetches every play in trakt history.
+func (s *Syncer) traktPlays(ctx context.Context) (media.Items, error) {
+	history, err := s.traktClient.HistoryGetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failure fetching trakt history: %w", err)
+	}
+	history = s.resolver.imdbIDs(history)
+	plays := make(media.Items, 0, len(history))
+	for _, it := range history {
+		plays = append(plays, it.ToMedia())
+	}
+	return plays, nil
+}
+
+// showIMDbID returns the imdb id of the show of an episode, if it has any.
+func showIMDbID(it media.Item) string {
+	if it.Type != media.TypeEpisode || it.Show == nil {
+		return ""
+	}
+	return it.Show.IMDb
+}
@@ -0,0 +1,131 @@
+package syncer
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+func play(imdbID string, playID int64, watchedAt *time.Time) media.Item {
+	return media.Item{
+		Type:      media.TypeMovie,
+		IDs:       media.IDs{IMDb: imdbID},
+		WatchedAt: watchedAt,
+		PlayID:    playID,
+	}
+}
+
+func episodePlay(showIMDbID string, playID int64, watchedAt *time.Time) media.Item {
+	return media.Item{
+		Type:      media.TypeEpisode,
+		Show:      &media.IDs{IMDb: showIMDbID},
+		WatchedAt: watchedAt,
+		PlayID:    playID,
+	}
+}
+
+func TestLastPlayID(t *testing.T) {
+	tests := []struct {
+		name    string
+		history map[string]trakt.Items
+		want    int64
+	}{
+		{
+			name:    "empty history",
+			history: map[string]trakt.Items{},
+			want:    0,
+		},
+		{
+			name: "highest id across items",
+			history: map[string]trakt.Items{
+				"tt0000001": {{ID: 3}, {ID: 7}},
+				"tt0000002": {{ID: 5}},
+			},
+			want: 7,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastPlayID(tt.history); got != tt.want {
+				t.Errorf("lastPlayID() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddedPlays(t *testing.T) {
+	monday := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	mondayMillis := monday.Add(500 * time.Millisecond)
+	tests := []struct {
+		name       string
+		plays      media.Items
+		items      media.Items
+		lastPlayID int64
+		want       []int64
+	}{
+		{
+			name:       "plays up to the last play id are left alone",
+			plays:      media.Items{play("tt0000001", 10, &monday), play("tt0000001", 11, &monday)},
+			items:      media.Items{play("tt0000001", 0, &monday)},
+			lastPlayID: 10,
+			want:       []int64{11},
+		},
+		{
+			name:       "plays of other items are left alone",
+			plays:      media.Items{play("tt0000001", 11, &monday), play("tt0000002", 12, &monday)},
+			items:      media.Items{play("tt0000001", 0, &monday)},
+			lastPlayID: 10,
+			want:       []int64{11},
+		},
+		{
+			name:       "plays at other dates are left alone",
+			plays:      media.Items{play("tt0000001", 11, &tuesday)},
+			items:      media.Items{play("tt0000001", 0, &monday)},
+			lastPlayID: 10,
+			want:       []int64{},
+		},
+		{
+			name:       "watch dates are compared to the second",
+			plays:      media.Items{play("tt0000001", 11, &mondayMillis)},
+			items:      media.Items{play("tt0000001", 0, &monday)},
+			lastPlayID: 10,
+			want:       []int64{11},
+		},
+		{
+			name:       "episode plays match the imdb id of their show",
+			plays:      media.Items{episodePlay("tt0000003", 11, &monday), episodePlay("tt0000004", 12, &monday)},
+			items:      media.Items{{Type: media.TypeShow, IDs: media.IDs{IMDb: "tt0000003"}, WatchedAt: &monday}},
+			lastPlayID: 10,
+			want:       []int64{11},
+		},
+		{
+			name:       "items without a watch date match any date after the last play id",
+			plays:      media.Items{play("tt0000001", 10, &monday), play("tt0000001", 11, &tuesday)},
+			items:      media.Items{play("tt0000001", 0, nil)},
+			lastPlayID: 10,
+			want:       []int64{11},
+		},
+		{
+			name:       "items without a watch date match nothing when the last play id is unknown",
+			plays:      media.Items{play("tt0000001", 11, &tuesday)},
+			items:      media.Items{play("tt0000001", 0, nil)},
+			lastPlayID: 0,
+			want:       []int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]int64, 0)
+			for _, it := range addedPlays(tt.plays, tt.items, tt.lastPlayID) {
+				got = append(got, it.PlayID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("addedPlays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package syncer
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
+)
+
+const (
+	journalVersion = 3
+
+	reasonUndoAdded   = "added by the undone sync"
+	reasonUndoRemoved = "removed by the undone sync"
+	reasonUndoRating  = "rating changed by the undone sync"
+)
+
+// Journal records the changesets the last applied plan actually executed,
+// so that they can be undone. It is written after the additions and after the
+// removals of every changeset, hence a plan that failed halfway can be undone
+// up to where it got.
+type Journal struct {
+	Version    int        `json:"version"`
+	AppliedAt  time.Time  `json:"applied_at"`
+	Changesets Changesets `json:"changesets"`
+	// Snapshot is the sync state as it was before the plan got applied, which
+	// is restored on undo so that a bidirectional sync doesn't mistake the
+	// undone changes for changes made by the user.
+	Snapshot *state.Snapshot `json:"snapshot,omitempty"`
+	// UndoneAt is set once the changes were undone, which can only happen once.
+	UndoneAt *time.Time `json:"undone_at,omitempty"`
+}
+
+func newJournal() *Journal {
+	return &Journal{
+		Version:    journalVersion,
+		AppliedAt:  time.Now().UTC(),
+		Changesets: make(Changesets, 0),
+	}
+}
+
+// RequiresIMDb reports whether undoing the journal writes to imdb.
+func (j *Journal) RequiresIMDb() bool {
+	return slices.ContainsFunc(j.Changesets, func(cs Changeset) bool {
+		return cs.Provider == ProviderIMDb
+	})
+}
+
+// Undo returns a plan with the inverse of every changeset in the journal, in
+// reverse order: added items are removed, removed items are added back and
+// overwritten ratings are restored to their previous values. History is undone
+// play by play, so that the plays from before the sync are left alone, which
+// is why the plan is applied by Syncer.Undo.
+func (j *Journal) Undo() *Plan {
+	plan := newPlan()
+	plan.Snapshot = j.Snapshot
+	plan.Undo = true
+	for _, cs := range slices.Backward(j.Changesets) {
+		if cs.Resource == ResourceHistory {
+			plan.add(undoHistory(cs))
+			continue
+		}
+		previous := make(map[string]media.Item, len(cs.Previous))
+		for _, it := range cs.Previous {
+			previous[it.IDs.IMDb] = it
+		}
+		inverse := Changeset{
+			Provider:    cs.Provider,
+			Resource:    cs.Resource,
+			ListID:      cs.ListID,
+			ListName:    cs.ListName,
+			TraktListID: cs.TraktListID,
+			Add:         make(media.Items, 0, len(cs.Remove)),
+			Remove:      make(media.Items, 0, len(cs.Add)),
+			Reasons:     make(map[string]string, len(cs.Add)+len(cs.Remove)),
+		}
+		for _, it := range cs.Add {
+			id := it.IDs.IMDb
+			if id == "" {
+				continue
+			}
+			if prev, ok := previous[id]; ok {
+				inverse.Add = append(inverse.Add, prev)
+				inverse.Reasons[id] = reasonUndoRating
+				continue
+			}
+			inverse.Remove = append(inverse.Remove, it)
+			inverse.Reasons[id] = reasonUndoAdded
+		}
+		for _, it := range cs.Remove {
+			id := it.IDs.IMDb
+			if id == "" {
+				continue
+			}
+			inverse.Add = append(inverse.Add, it)
+			inverse.Reasons[id] = reasonUndoRemoved
+		}
+		plan.add(inverse)
+	}
+	return plan
+}
+
+// undoHistory removes the items that cs added, which Syncer.Undo narrows down
+// to the plays they created, and adds back the plays that it removed at the
+// dates they were watched at.
+func undoHistory(cs Changeset) Changeset {
+	inverse := Changeset{
+		Provider:   cs.Provider,
+		Resource:   cs.Resource,
+		Add:        make(media.Items, 0, len(cs.RemovedPlays)),
+		Remove:     slices.Clone(cs.Add),
+		Reasons:    make(map[string]string, len(cs.Add)+len(cs.RemovedPlays)),
+		LastPlayID: cs.LastPlayID,
+	}
+	for _, it := range cs.Add {
+		if id := it.IDs.IMDb; id != "" {
+			inverse.Reasons[id] = reasonUndoAdded
+		}
+	}
+	for _, play := range cs.RemovedPlays {
+		if id := cmp.Or(play.IDs.IMDb, showIMDbID(play)); id != "" {
+			inverse.Reasons[id] = reasonUndoRemoved
+		}
+		// the play is added back as a new one
+		play.PlayID = 0
+		inverse.Add = append(inverse.Add, play)
+	}
+	return inverse
+}
+
+// Undo reverts the changes recorded in the journal and marks it as undone, so
+// that they aren't reverted twice. The items that history changesets added are
+// narrowed down to the plays they created first, which takes a single fetch of
+// the whole trakt history.
+func (s *Syncer) Undo(ctx context.Context, j *Journal) error {
+	if j.UndoneAt != nil {
+		err := fmt.Errorf("the sync applied at %s was already undone at %s", j.AppliedAt.Format(time.RFC3339), j.UndoneAt.Format(time.RFC3339))
+		s.logger.Error("failure undoing last sync", logger.Error(err))
+		return err
+	}
+	plan := j.Undo()
+	var plays media.Items
+	for i := range plan.Changesets {
+		cs := &plan.Changesets[i]
+		if cs.Resource != ResourceHistory || len(cs.Remove) == 0 {
+			continue
+		}
+		if plays == nil {
+			var err error
+			if plays, err = s.traktPlays(ctx); err != nil {
+				s.logger.Error("failure fetching trakt history", logger.Error(err))
+				return err
+			}
+		}
+		cs.Remove = addedPlays(plays, cs.Remove, cs.LastPlayID)
+		for _, play := range cs.Remove {
+			if id := play.IDs.IMDb; id != "" && cs.Reasons[id] == "" {
+				cs.Reasons[id] = reasonUndoAdded
+			}
+		}
+	}
+	if err := s.Apply(ctx, plan); err != nil {
+		return err
+	}
+	undoneAt := time.Now().UTC()
+	j.UndoneAt = &undoneAt
+	if err := j.WriteFile(*s.conf.JournalFile); err != nil {
+		s.logger.Error("failure writing journal", logger.Error(err))
+		return err
+	}
+	return nil
+}
+
+// add records cs unless it changed nothing, in which case the journal of the
+// previous run is better kept around.
+func (j *Journal) add(cs Changeset) bool {
+	if len(cs.Add) == 0 && len(cs.Remove) == 0 {
+		return false
+	}
+	j.Changesets = append(j.Changesets, cs)
+	return true
+}
+
+func (j *Journal) WriteFile(path string) error {
+	b, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failure marshalling journal: %w", err)
+	}
+	if err = os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("failure writing journal file %s: %w", path, err)
+	}
+	return nil
+}
+
+func LoadJournal(path string) (*Journal, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading journal file %s: %w", path, err)
+	}
+	var j Journal
+	if err = json.Unmarshal(b, &j); err != nil {
+		return nil, fmt.Errorf("failure unmarshalling journal file %s: %w", path, err)
+	}
+	if j.Version != journalVersion {
+		return nil, fmt.Errorf("unsupported journal version %d, expected %d", j.Version, journalVersion)
+	}
+	return &j, nil
+}
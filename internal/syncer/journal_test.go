@@ -0,0 +1,139 @@
+package syncer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+)
+
+func rated(imdbID string, rating float64) media.Item {
+	return media.Item{
+		Type:   media.TypeMovie,
+		IDs:    media.IDs{IMDb: imdbID},
+		Rating: &rating,
+	}
+}
+
+func imdbIDs(items media.Items) []string {
+	ids := make([]string, 0, len(items))
+	for _, it := range items {
+		ids = append(ids, it.IDs.IMDb)
+	}
+	return ids
+}
+
+func TestJournal_Undo(t *testing.T) {
+	watchedAt := time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		journal Journal
+		want    Changesets
+	}{
+		{
+			name: "added items are removed and removed items are added back",
+			journal: Journal{Changesets: Changesets{{
+				Provider: ProviderTrakt,
+				Resource: ResourceWatchlist,
+				Add:      media.Items{rated("tt0000001", 0)},
+				Remove:   media.Items{rated("tt0000002", 0)},
+			}}},
+			want: Changesets{{
+				Provider: ProviderTrakt,
+				Resource: ResourceWatchlist,
+				Add:      media.Items{rated("tt0000002", 0)},
+				Remove:   media.Items{rated("tt0000001", 0)},
+				Reasons:  map[string]string{"tt0000001": reasonUndoAdded, "tt0000002": reasonUndoRemoved},
+			}},
+		},
+		{
+			name: "overwritten ratings are restored",
+			journal: Journal{Changesets: Changesets{{
+				Provider: ProviderTrakt,
+				Resource: ResourceRatings,
+				Add:      media.Items{rated("tt0000001", 8), rated("tt0000002", 6)},
+				Previous: media.Items{rated("tt0000001", 7)},
+			}}},
+			want: Changesets{{
+				Provider: ProviderTrakt,
+				Resource: ResourceRatings,
+				Add:      media.Items{rated("tt0000001", 7)},
+				Remove:   media.Items{rated("tt0000002", 6)},
+				Reasons:  map[string]string{"tt0000001": reasonUndoRating, "tt0000002": reasonUndoAdded},
+			}},
+		},
+		{
+			name: "changesets are undone in reverse order",
+			journal: Journal{Changesets: Changesets{
+				{Provider: ProviderTrakt, Resource: ResourceList, ListID: "ls000000001", Add: media.Items{rated("tt0000001", 0)}},
+				{Provider: ProviderTrakt, Resource: ResourceList, ListID: "ls000000002", Add: media.Items{rated("tt0000002", 0)}},
+			}},
+			want: Changesets{
+				{Provider: ProviderTrakt, Resource: ResourceList, ListID: "ls000000002", Remove: media.Items{rated("tt0000002", 0)}},
+				{Provider: ProviderTrakt, Resource: ResourceList, ListID: "ls000000001", Remove: media.Items{rated("tt0000001", 0)}},
+			},
+		},
+		{
+			name: "history adds back the removed plays as new plays",
+			journal: Journal{Changesets: Changesets{{
+				Provider:     ProviderTrakt,
+				Resource:     ResourceHistory,
+				Add:          media.Items{play("tt0000001", 0, &watchedAt)},
+				RemovedPlays: media.Items{play("tt0000002", 42, &watchedAt)},
+				LastPlayID:   41,
+			}}},
+			want: Changesets{{
+				Provider:   ProviderTrakt,
+				Resource:   ResourceHistory,
+				Add:        media.Items{play("tt0000002", 0, &watchedAt)},
+				Remove:     media.Items{play("tt0000001", 0, &watchedAt)},
+				Reasons:    map[string]string{"tt0000001": reasonUndoAdded, "tt0000002": reasonUndoRemoved},
+				LastPlayID: 41,
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := tt.journal.Undo()
+			if !plan.Undo {
+				t.Error("Undo() returned a plan that isn't marked as undo")
+			}
+			if len(plan.Changesets) != len(tt.want) {
+				t.Fatalf("Undo() returned %d changesets, want %d", len(plan.Changesets), len(tt.want))
+			}
+			for i, got := range plan.Changesets {
+				want := tt.want[i]
+				if got.ListID != want.ListID || got.LastPlayID != want.LastPlayID {
+					t.Errorf("changeset %d targets %q with last play id %d, want %q with %d", i, got.ListID, got.LastPlayID, want.ListID, want.LastPlayID)
+				}
+				assertItems(t, "add", got.Add, want.Add)
+				assertItems(t, "remove", got.Remove, want.Remove)
+				for id, reason := range want.Reasons {
+					if got.Reasons[id] != reason {
+						t.Errorf("changeset %d reason of %s = %q, want %q", i, id, got.Reasons[id], reason)
+					}
+				}
+			}
+		})
+	}
+}
+
+func assertItems(t *testing.T, name string, got, want media.Items) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", name, imdbIDs(got), imdbIDs(want))
+		return
+	}
+	for i := range got {
+		if got[i].IDs.IMDb != want[i].IDs.IMDb || got[i].PlayID != want[i].PlayID || !equalRatings(got[i].Rating, want[i].Rating) {
+			t.Errorf("%s[%d] = %+v, want %+v", name, i, got[i], want[i])
+		}
+	}
+}
+
+func equalRatings(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
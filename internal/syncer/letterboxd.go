@@ -0,0 +1,66 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/letterboxd"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+)
+
+// letterboxdTarget writes the import files that letterboxd offers for
+// watchlists, lists and ratings, which have to be imported by hand.
+type letterboxdTarget struct {
+	directory string
+	logger    *slog.Logger
+}
+
+func (t *letterboxdTarget) Supports(r Resource) bool {
+	return r != ResourceHistory
+}
+
+// accepts leaves out shows, episodes and people, since letterboxd only knows films.
+func (t *letterboxdTarget) accepts(it media.Item) bool {
+	return it.Type == media.TypeMovie
+}
+
+func (t *letterboxdTarget) Apply(_ context.Context, cs *Changeset) error {
+	if len(cs.Add) == 0 {
+		t.logger.Info("no letterboxd films to write", "target", cs.target())
+		return nil
+	}
+	if err := os.MkdirAll(t.directory, 0o755); err != nil {
+		return fmt.Errorf("failure creating letterboxd directory %s: %w", t.directory, err)
+	}
+	films := make(letterboxd.Films, 0, len(cs.Add))
+	for _, it := range cs.Add {
+		film := letterboxd.Film{
+			IMDbID: it.IDs.IMDb,
+			Title:  it.Title,
+			Year:   it.Year,
+		}
+		if it.Rating != nil {
+			film.Rating = letterboxd.RatingFromIMDb(*it.Rating)
+		}
+		if it.RatedAt != nil {
+			film.WatchedDate = it.RatedAt.Format(time.DateOnly)
+		}
+		films = append(films, film)
+	}
+	var err error
+	path := filepath.Join(t.directory, letterboxd.FileName(cs.Resource == ResourceRatings, cs.Resource == ResourceWatchlist, cs.ListName))
+	if cs.Resource == ResourceRatings {
+		err = letterboxd.WriteRatings(path, films)
+	} else {
+		err = letterboxd.WriteFilms(path, films)
+	}
+	if err != nil {
+		return err
+	}
+	t.logger.Info("wrote letterboxd import file", "path", path, "count", len(films))
+	return nil
+}
@@ -0,0 +1,58 @@
+package syncer
+
+import (
+	"slices"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
+)
+
+// skipNotFound leaves out the items that the provider could not find during
+// a recent sync, since adding them again is bound to fail the same way until
+// the provider learns about them. They are retried once SYNC_NOTFOUNDRETRY
+// has passed, or as soon as the item is added to the mapping file.
+func (s *Syncer) skipNotFound(cs *Changeset) {
+	if s.state == nil || *s.conf.NotFoundRetry <= 0 {
+		return
+	}
+	now := time.Now()
+	cs.Add = slices.DeleteFunc(cs.Add, func(it media.Item) bool {
+		at, ok := s.state.NotFoundAt(string(cs.Provider), it.IDs.IMDb)
+		if !ok || now.Sub(at) >= *s.conf.NotFoundRetry {
+			return false
+		}
+		if _, ok = s.resolver.mappings[it.IDs.IMDb]; ok {
+			// the mapping might have been added since to fix the item
+			return false
+		}
+		s.logger.Info("skipping item trakt could not find during a previous sync", "imdbID", it.IDs.IMDb, "type", it.Type, "target", cs.target(), "retryAt", at.Add(*s.conf.NotFoundRetry))
+		return true
+	})
+}
+
+// persistNotFound records the items of an applied changeset that trakt could
+// not find, and forgets the added items that it found since.
+func persistNotFound(st *state.State, cs Changeset) {
+	if cs.Provider != ProviderTrakt {
+		return
+	}
+	now := time.Now().UTC()
+	missing := make(map[string]struct{}, len(cs.NotFound))
+	for _, it := range cs.NotFound {
+		missing[it.IDs.IMDb] = struct{}{}
+		st.AddNotFound(state.NotFound{
+			Provider:    string(cs.Provider),
+			Resource:    string(cs.Resource),
+			Target:      cs.target(),
+			Item:        it,
+			FirstSeenAt: now,
+			LastSeenAt:  now,
+		})
+	}
+	for _, it := range cs.Add {
+		if _, ok := missing[it.IDs.IMDb]; !ok {
+			st.RemoveNotFound(string(cs.Provider), it.IDs.IMDb)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
+)
+
+const planVersion = 3
+
+const (
+	ProviderIMDb       Provider = "imdb"
+	ProviderLetterboxd Provider = "letterboxd"
+	ProviderTrakt      Provider = "trakt"
+	ResourceHistory    Resource = "history"
+	ResourceList       Resource = "list"
+	ResourceRatings    Resource = "ratings"
+	ResourceWatchlist  Resource = "watchlist"
+)
+
+type Provider string
+
+type Resource string
+
+// Plan is the full set of changes a sync would make. It is computed
+// without writing anything, so it can be persisted, reviewed by a human and
+// applied later exactly as it was computed.
+type Plan struct {
+	Version    int        `json:"version"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Changesets Changesets `json:"changesets"`
+	// Snapshot is persisted as the sync state once the plan is applied, which
+	// only bidirectional syncs need.
+	Snapshot *state.Snapshot `json:"snapshot,omitempty"`
+	// Undo marks a plan that reverts the changes of the last applied plan,
+	// which isn't checked against the removal thresholds again.
+	Undo bool `json:"undo,omitempty"`
+}
+
+// Changeset holds the changes to a single resource of the provider being
+// written to.
+type Changeset struct {
+	Provider    Provider `json:"provider"`
+	Resource    Resource `json:"resource"`
+	ListID      string   `json:"list_id,omitempty"`
+	ListName    string   `json:"list_name,omitempty"`
+	TraktListID int      `json:"trakt_list_id,omitempty"`
+	// TargetSize is how many items the target held when the changeset was
+	// planned, which the removals are checked against once more when the
+	// plan is applied.
+	TargetSize int         `json:"target_size,omitempty"`
+	Add        media.Items `json:"add"`
+	Remove     media.Items `json:"remove"`
+	// Previous holds the ratings that the added items overwrite, so that they
+	// can be restored on undo.
+	Previous media.Items `json:"previous,omitempty"`
+	// Reasons explains why each item is added or removed, keyed by imdb id.
+	Reasons map[string]string `json:"reasons,omitempty"`
+	// DryRun marks the changes of a target in dry-run mode, which are only
+	// reported when the plan is applied.
+	DryRun bool `json:"dry_run,omitempty"`
+	// NotFound holds the added items that trakt could not match when the
+	// changeset was applied.
+	NotFound media.Items `json:"not_found,omitempty"`
+	// RemovedPlays holds the plays that a history changeset removes, so that
+	// undoing it adds them back at the dates they were watched at.
+	RemovedPlays media.Items `json:"removed_plays,omitempty"`
+	// LastPlayID is the id of the latest play in trakt history when a history
+	// changeset was planned. Trakt numbers plays in the order they are added,
+	// so undoing the changeset only removes plays with higher ids.
+	LastPlayID int64 `json:"last_play_id,omitempty"`
+}
+
+// steps splits the changeset into its additions and its removals, which are
+// applied and journaled one after the other, so that a changeset that failed
+// halfway can still be undone up to where it got.
+func (cs Changeset) steps() Changesets {
+	if len(cs.Add) == 0 || len(cs.Remove) == 0 {
+		return Changesets{cs}
+	}
+	add, remove := cs, cs
+	add.Remove, add.RemovedPlays = make(media.Items, 0), nil
+	remove.Add, remove.Previous = make(media.Items, 0), nil
+	return Changesets{add, remove}
+}
+
+// target describes what the changeset modifies in human-readable terms.
+func (cs *Changeset) target() string {
+	if cs.Resource == ResourceList {
+		return cs.ListName
+	}
+	return string(cs.Resource)
+}
+
+// reportAttrs describes a single item of the changeset, so that it can be
+// reviewed without having to look up its imdb id.
+func (cs *Changeset) reportAttrs(it media.Item) []any {
+	id := it.IDs.IMDb
+	return []any{
+		"provider", cs.Provider,
+		"imdbID", id,
+		"title", it.Title,
+		"year", it.Year,
+		"type", it.Type,
+		"target", cs.target(),
+		"reason", cs.Reasons[id],
+	}
+}
+
+type Changesets []Changeset
+
+// RequiresIMDb reports whether applying the plan writes to imdb, in which
+// case an imdb client is needed on top of the trakt one.
+func (p *Plan) RequiresIMDb() bool {
+	for _, cs := range p.Changesets {
+		if cs.Provider == ProviderIMDb {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plan) changesTrakt() bool {
+	for _, cs := range p.Changesets {
+		if cs.Provider == ProviderTrakt && (len(cs.Add) > 0 || len(cs.Remove) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func newPlan() *Plan {
+	return &Plan{
+		Version:    planVersion,
+		CreatedAt:  time.Now().UTC(),
+		Changesets: make(Changesets, 0),
+	}
+}
+
+func (p *Plan) add(cs Changeset) {
+	if cs.Add == nil {
+		cs.Add = make(media.Items, 0)
+	}
+	if cs.Remove == nil {
+		cs.Remove = make(media.Items, 0)
+	}
+	p.Changesets = append(p.Changesets, cs)
+}
+
+func (p *Plan) WriteFile(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failure marshalling plan: %w", err)
+	}
+	if err = os.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("failure writing plan file %s: %w", path, err)
+	}
+	return nil
+}
+
+func LoadPlan(path string) (*Plan, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failure reading plan file %s: %w", path, err)
+	}
+	var p Plan
+	if err = json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("failure unmarshalling plan file %s: %w", path, err)
+	}
+	if p.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version %d, expected %d", p.Version, planVersion)
+	}
+	return &p, nil
+}
@@ -0,0 +1,171 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/mapping"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+// resolver matches the items that trakt can't find by their imdb id on their
+// other ids. Those come from the mapping file maintained by the user, from the
+// cache of the ids resolved by previous syncs, and from trakt's id search.
+type resolver struct {
+	client   trakt.API
+	logger   *slog.Logger
+	mappings mapping.Mappings
+	// cache is shared with the sync state, so that the ids resolved while a
+	// plan is applied get persisted along with it.
+	cache mapping.Mappings
+}
+
+func newResolver(conf *appconfig.Config, logger *slog.Logger, client trakt.API) (*resolver, error) {
+	mappings, err := mapping.Load(*conf.Sync.MappingFile)
+	if err != nil {
+		return nil, fmt.Errorf("failure loading id mappings: %w", err)
+	}
+	return &resolver{
+		client:   client,
+		logger:   logger,
+		mappings: mappings,
+	}, nil
+}
+
+// lookup returns the known mapping of an imdb id. The mapping file takes
+// precedence over the cache, since it is how a bad resolution gets corrected.
+func (r *resolver) lookup(id string) (mapping.Mapping, bool) {
+	if m, ok := r.mappings[id]; ok {
+		return m, true
+	}
+	m, ok := r.cache[id]
+	return m, ok
+}
+
+// apply sets the known ids on the items that are added to trakt, so that
+// trakt matches them on the first try.
+func (r *resolver) apply(plan *Plan) {
+	for i := range plan.Changesets {
+		cs := &plan.Changesets[i]
+		if cs.Provider != ProviderTrakt {
+			continue
+		}
+		for j := range cs.Add {
+			if m, ok := r.lookup(cs.Add[j].IDs.IMDb); ok {
+				m.Apply(&cs.Add[j])
+			}
+		}
+	}
+}
+
+// imdbIDs sets the imdb id of the trakt items whose trakt id a mapping points
+// to, since trakt might not know their imdb id or know them by another one.
+// Either way they have to be matched with the imdb item they were added for.
+// The items are copied, since the fetched trakt items are cached as they are.
+func (r *resolver) imdbIDs(its trakt.Items) trakt.Items {
+	byTraktID := make(map[string]string)
+	for _, mappings := range []mapping.Mappings{r.cache, r.mappings} {
+		for id, m := range mappings {
+			if m.Trakt != 0 {
+				byTraktID[traktKey(m.Type, m.Trakt)] = id
+			}
+		}
+	}
+	if len(byTraktID) == 0 {
+		return its
+	}
+	its = slices.Clone(its)
+	for i := range its {
+		id, err := its[i].GetItemID()
+		if err != nil || id == nil {
+			continue
+		}
+		mi := its[i].ToMedia()
+		// mappings without a type match the trakt id of any type
+		for _, key := range []string{traktKey(mi.Type, mi.IDs.Trakt), traktKey("", mi.IDs.Trakt)} {
+			if imdbID, ok := byTraktID[key]; ok {
+				*id = imdbID
+				break
+			}
+		}
+	}
+	return its
+}
+
+// ignored reports whether the mapping file excludes the item with the given
+// imdb id from the sync.
+func (r *resolver) ignored(id string) bool {
+	return r.mappings[id].Ignore
+}
+
+func traktKey(itType media.Type, traktID int) string {
+	return fmt.Sprintf("%s/%d", itType, traktID)
+}
+
+// resolve searches trakt for the given items by their imdb id across every
+// type, which finds the titles that imdb and trakt disagree on the type of,
+// and then by the tmdb and tvdb ids they got from the mapping file. The items
+// that are found get their type and trakt ids set, and are returned.
+func (r *resolver) resolve(ctx context.Context, items []*media.Item) []*media.Item {
+	resolved := make([]*media.Item, 0, len(items))
+	for _, it := range items {
+		m, ok, err := r.search(ctx, *it)
+		if err != nil {
+			r.logger.Warn("failure searching trakt for item", "imdbID", it.IDs.IMDb, logger.Error(err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+		r.logger.Info("resolved item trakt could not find by imdb id", "imdbID", it.IDs.IMDb, "type", m.Type, "traktID", m.Trakt)
+		m.Apply(it)
+		resolved = append(resolved, it)
+	}
+	return resolved
+}
+
+func (r *resolver) search(ctx context.Context, it media.Item) (mapping.Mapping, bool, error) {
+	type query struct {
+		idType string
+		id     string
+		itType string
+	}
+	queries := []query{{idType: "imdb", id: it.IDs.IMDb}}
+	if it.IDs.TMDb != 0 {
+		queries = append(queries, query{idType: "tmdb", id: strconv.Itoa(it.IDs.TMDb), itType: string(it.Type)})
+	}
+	if it.IDs.TVDb != 0 {
+		queries = append(queries, query{idType: "tvdb", id: strconv.Itoa(it.IDs.TVDb), itType: string(it.Type)})
+	}
+	for _, q := range queries {
+		results, err := r.client.Search(ctx, q.idType, q.id, q.itType)
+		if err != nil {
+			return mapping.Mapping{}, false, err
+		}
+		for _, result := range results {
+			if mi := result.ToMedia(); mi.IDs.Trakt != 0 {
+				return mapping.FromMedia(mi), true, nil
+			}
+		}
+	}
+	return mapping.Mapping{}, false, nil
+}
+
+// remember caches the mappings of the resolved items that trakt found, so that
+// the following syncs send their trakt ids right away.
+func (r *resolver) remember(resolved []*media.Item, missing map[string]struct{}) {
+	if r.cache == nil {
+		return
+	}
+	for _, it := range resolved {
+		if _, ok := missing[it.IDs.IMDb]; !ok {
+			r.cache[it.IDs.IMDb] = mapping.FromMedia(*it)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+)
+
+// planIMDbLists plans the changes that bring the imdb watchlist and lists in
+// line with their trakt counterparts. Imdb lists without a trakt list of the
+// same name are left untouched, since there is nothing to sync them from.
+func (s *Syncer) planIMDbLists(plan *Plan) {
+	if !*s.conf.Watchlist {
+		s.logger.Info("skipping watchlist sync")
+	}
+	if !*s.conf.Lists {
+		s.logger.Info("skipping lists sync")
+	}
+	if !*s.conf.Watchlist && !*s.conf.Lists {
+		return
+	}
+	for _, lid := range slices.Sorted(maps.Keys(s.user.imdbLists)) {
+		imdbList := s.user.imdbLists[lid]
+		if !s.listInScope(imdbList) {
+			continue
+		}
+		traktList, ok := s.user.traktLists[lid]
+		if !ok {
+			s.logger.Info("skipping imdb list without a matching trakt list", "id", lid, "name", imdbList.ListName)
+			continue
+		}
+		diff := itemsDifference(media.ByID(traktList.ListItems), media.ByID(imdbList.ListItems), ProviderTrakt, ProviderIMDb)
+		cs := Changeset{
+			Provider: ProviderIMDb,
+			Resource: ResourceList,
+			ListID:   imdbList.ListID,
+			ListName: imdbList.ListName,
+			Add:      diff.Add,
+			Remove:   diff.Remove,
+			Reasons:  diff.Reasons,
+		}
+		if imdbList.IsWatchlist {
+			cs.Resource, cs.ListID, cs.ListName = ResourceWatchlist, "", ""
+		}
+		s.addChangeset(plan, cs)
+	}
+}
+
+func (s *Syncer) planIMDbRatings(plan *Plan) {
+	if !*s.conf.Ratings {
+		s.logger.Info("skipping ratings sync")
+		return
+	}
+	// ratings are compared on the trakt scale, since several imdb ratings might
+	// convert to the same trakt rating, and only the changed ones are converted back
+	diff := itemsDifference(toMedia(s.user.traktRatings), convertRatings(toMedia(s.user.imdbRatings), s.ratings.ToTrakt), ProviderTrakt, ProviderIMDb)
+	s.restoreIMDbRatings(diff.Add, diff.Remove)
+	s.addChangeset(plan, Changeset{
+		Provider: ProviderIMDb,
+		Resource: ResourceRatings,
+		Add:      diff.Add,
+		Remove:   diff.Remove,
+		Reasons:  diff.Reasons,
+	})
+}
+
+type imdbTarget struct {
+	client imdb.API
+	logger *slog.Logger
+}
+
+// Supports leaves out history, since imdb has none.
+func (t *imdbTarget) Supports(r Resource) bool {
+	return r != ResourceHistory
+}
+
+func (t *imdbTarget) Apply(_ context.Context, cs *Changeset) error {
+	if t.client == nil {
+		return fmt.Errorf("changesets for imdb can't be applied without an imdb client")
+	}
+	add, remove := toIMDbItems(cs.Add), toIMDbItems(cs.Remove)
+	switch cs.Resource {
+	case ResourceWatchlist:
+		if len(add) > 0 {
+			if err := t.client.WatchlistItemsAdd(add); err != nil {
+				return fmt.Errorf("failure adding items to imdb watchlist: %w", err)
+			}
+		} else {
+			t.logger.Info("no imdb watchlist items to add")
+		}
+		if len(remove) > 0 {
+			if err := t.client.WatchlistItemsRemove(remove); err != nil {
+				return fmt.Errorf("failure removing imdb watchlist items: %w", err)
+			}
+		} else {
+			t.logger.Info("no imdb watchlist items to remove")
+		}
+	case ResourceList:
+		if len(add) > 0 {
+			if err := t.client.ListItemsAdd(cs.ListID, add); err != nil {
+				return fmt.Errorf("failure adding items to imdb list %s: %w", cs.ListName, err)
+			}
+		} else {
+			t.logger.Info("no imdb list items to add", "name", cs.ListName)
+		}
+		if len(remove) > 0 {
+			if err := t.client.ListItemsRemove(cs.ListID, remove); err != nil {
+				return fmt.Errorf("failure removing imdb list items from %s: %w", cs.ListName, err)
+			}
+		} else {
+			t.logger.Info("no imdb list items to remove", "name", cs.ListName)
+		}
+	case ResourceRatings:
+		if len(add) > 0 {
+			if err := t.client.RatingsAdd(add); err != nil {
+				return fmt.Errorf("failure adding imdb ratings: %w", err)
+			}
+		} else {
+			t.logger.Info("no imdb ratings to add")
+		}
+		if len(remove) > 0 {
+			if err := t.client.RatingsRemove(remove); err != nil {
+				return fmt.Errorf("failure removing imdb ratings: %w", err)
+			}
+		} else {
+			t.logger.Info("no imdb ratings to remove")
+		}
+	default:
+		return fmt.Errorf("changesets for imdb %s are not supported", cs.Resource)
+	}
+	return nil
+}
+
+func toIMDbItems(mis media.Items) imdb.Items {
+	items := make(imdb.Items, 0, len(mis))
+	for _, mi := range mis {
+		if item, ok := imdb.NewItemFromMedia(mi); ok {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
@@ -1,14 +1,22 @@
 package syncer
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
 
+	"github.com/cecobask/imdb-trakt-sync/internal/backup"
 	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
 	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
 	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+	"github.com/cecobask/imdb-trakt-sync/internal/mapping"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/rating"
+	"github.com/cecobask/imdb-trakt-sync/internal/state"
 	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
 )
 
@@ -19,6 +27,15 @@ type Syncer struct {
 	user        *user
 	conf        appconfig.Sync
 	authless    bool
+	state       *state.State
+	cache       *state.Cache
+	targets     map[Provider]Target
+	scopes      map[Provider]Scope
+	resolver    *resolver
+	ratings     *rating.Transform
+	// types maps imdb title types onto the types they are synced as, as
+	// configured by SYNC_TYPEMAPPING.
+	types map[string]string
 }
 
 type user struct {
@@ -26,6 +43,9 @@ type user struct {
 	imdbRatings  map[string]imdb.Item
 	traktLists   map[string]trakt.List
 	traktRatings map[string]trakt.Item
+	// traktHistory holds the plays in trakt history keyed by imdb id, once the
+	// whole history was fetched.
+	traktHistory map[string]trakt.Items
 }
 
 func NewSyncer(ctx context.Context, conf *appconfig.Config) (*Syncer, error) {
@@ -38,21 +58,36 @@ func NewSyncer(ctx context.Context, conf *appconfig.Config) (*Syncer, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failure initialising trakt client: %w", err)
 	}
+	resolver, err := newResolver(conf, log, traktClient)
+	if err != nil {
+		return nil, err
+	}
+	ratings, err := rating.NewTransform(conf.Sync)
+	if err != nil {
+		return nil, fmt.Errorf("failure setting up rating transform: %w", err)
+	}
+	types, err := appconfig.ParseTypeMapping(*conf.Sync.TypeMapping)
+	if err != nil {
+		return nil, fmt.Errorf("failure parsing type mapping: %w", err)
+	}
 	syncer := &Syncer{
 		logger:      log,
 		imdbClient:  imdbClient,
 		traktClient: traktClient,
 		user:        &user{},
 		conf:        conf.Sync,
-		authless:    *conf.IMDb.Auth == appconfig.IMDbAuthMethodNone,
-	}
-	if *conf.Sync.Ratings {
-		syncer.user.imdbRatings = make(map[string]imdb.Item)
-		syncer.user.traktRatings = make(map[string]trakt.Item)
-	}
-	if *conf.Sync.Lists || *conf.Sync.Watchlist {
-		syncer.user.imdbLists = make(map[string]imdb.List, len(*conf.IMDb.Lists))
-		syncer.user.traktLists = make(map[string]trakt.List, len(*conf.IMDb.Lists))
+		authless:    *conf.IMDb.Source == appconfig.IMDbSourceBrowser && *conf.IMDb.Auth == appconfig.IMDbAuthMethodNone,
+		targets:     newTargets(conf, log, traktClient, imdbClient, resolver),
+		scopes:      newScopes(conf),
+		resolver:    resolver,
+		ratings:     ratings,
+		types:       types,
+	}
+	syncer.user.imdbRatings = make(map[string]imdb.Item)
+	syncer.user.traktRatings = make(map[string]trakt.Item)
+	syncer.user.imdbLists = make(map[string]imdb.List, len(*conf.IMDb.Lists))
+	syncer.user.traktLists = make(map[string]trakt.List, len(*conf.IMDb.Lists))
+	if syncer.fetches(ResourceList) {
 		for _, lid := range *conf.IMDb.Lists {
 			syncer.user.imdbLists[lid] = imdb.List{ListID: lid}
 		}
@@ -60,54 +95,215 @@ func NewSyncer(ctx context.Context, conf *appconfig.Config) (*Syncer, error) {
 	return syncer, nil
 }
 
+// NewTraktSyncer returns a syncer without an imdb client, which is all that is
+// needed to apply a plan that was computed by an earlier run.
+func NewTraktSyncer(ctx context.Context, conf *appconfig.Config) (*Syncer, error) {
+	log := logger.NewLogger(os.Stdout)
+	traktClient, err := trakt.NewAPI(ctx, conf.Trakt, log)
+	if err != nil {
+		return nil, fmt.Errorf("failure initialising trakt client: %w", err)
+	}
+	resolver, err := newResolver(conf, log, traktClient)
+	if err != nil {
+		return nil, err
+	}
+	return &Syncer{
+		logger:      log,
+		traktClient: traktClient,
+		user:        &user{},
+		conf:        conf.Sync,
+		targets:     newTargets(conf, log, traktClient, nil, resolver),
+		scopes:      newScopes(conf),
+		resolver:    resolver,
+	}, nil
+}
+
 func (s *Syncer) Sync(ctx context.Context) error {
 	s.logger.Info("sync started")
-	if err := s.hydrate(ctx); err != nil {
+	plan, err := s.Plan(ctx)
+	if err != nil {
+		return err
+	}
+	if *s.conf.Mode == appconfig.SyncModeDryRun {
+		s.logPlan(plan)
+		s.logger.Info("sync completed")
+		return nil
+	}
+	if err = s.Apply(ctx, plan); err != nil {
+		return err
+	}
+	s.logger.Info("sync completed")
+	return nil
+}
+
+// Plan hydrates both clients and computes every change the sync would make,
+// without writing anything. Removals are left out in add-only mode, and are
+// checked against the removal thresholds otherwise.
+func (s *Syncer) Plan(ctx context.Context) (*Plan, error) {
+	st, err := state.Load(*s.conf.StateFile)
+	if err != nil {
+		s.logger.Error("failure loading sync state", logger.Error(err))
+		return nil, err
+	}
+	s.state = st
+	if s.state.Mappings == nil {
+		s.state.Mappings = make(mapping.Mappings)
+	}
+	s.resolver.cache = s.state.Mappings
+	if err = s.hydrate(ctx); err != nil {
 		s.logger.Error("failure hydrating imdb client", logger.Error(err))
+		return nil, err
+	}
+	s.dropExcluded()
+	plan := newPlan()
+	switch *s.conf.Direction {
+	case appconfig.SyncDirectionBidirectional:
+		s.planBidirectional(plan)
+	case appconfig.SyncDirectionTraktToIMDb:
+		s.planIMDbLists(plan)
+		s.planIMDbRatings(plan)
+		if *s.conf.History {
+			s.logger.Info("skipping history sync since imdb has no history")
+		}
+	default:
+		s.planLists(plan)
+		s.planRatings(plan)
+		if err = s.planHistory(ctx, plan); err != nil {
+			s.logger.Error("failure planning history", logger.Error(err))
+			return nil, err
+		}
+	}
+	s.resolveEpisodes(ctx, plan)
+	s.resolver.apply(plan)
+	s.planSnapshotTargets(plan)
+	s.measureTargets(plan)
+	if err = s.guardRemovals(plan, *s.conf.Mode != appconfig.SyncModeDryRun); err != nil {
+		s.logger.Error("failure guarding removals", logger.Error(err))
+		return nil, err
+	}
+	return plan, nil
+}
+
+// Apply executes the changesets of a plan, exactly as they were computed.
+// Trakt lists that did not exist at planning time are created. Every applied
+// changeset is recorded in the journal, so that the run can be undone, unless
+// the plan undoes a run itself. The snapshot of a bidirectional plan, the
+// trakt data cached for incremental syncs and the items trakt could not find
+// are only persisted after every changeset was applied.
+func (s *Syncer) Apply(ctx context.Context, plan *Plan) error {
+	if err := s.guardRemovals(plan, true); err != nil {
+		s.logger.Error("failure guarding removals", logger.Error(err))
 		return err
 	}
-	if err := s.syncLists(ctx); err != nil {
-		s.logger.Error("failure syncing lists", logger.Error(err))
+	if err := s.backup(ctx, plan); err != nil {
+		s.logger.Error("failure backing up trakt data", logger.Error(err))
 		return err
 	}
-	if err := s.syncRatings(ctx); err != nil {
-		s.logger.Error("failure syncing ratings", logger.Error(err))
+	journal := newJournal()
+	var st *state.State
+	if plan.Snapshot != nil || plan.changesTrakt() || s.caches() {
+		var err error
+		if st, err = state.Load(*s.conf.StateFile); err != nil {
+			s.logger.Error("failure loading sync state", logger.Error(err))
+			return err
+		}
+		if st.Mappings == nil {
+			st.Mappings = make(mapping.Mappings)
+		}
+		s.resolver.cache = st.Mappings
+	}
+	if plan.Snapshot != nil {
+		journal.Snapshot = st.Snapshot
+		if journal.Snapshot == nil {
+			journal.Snapshot = state.NewSnapshot()
+		}
+	}
+	for _, cs := range plan.Changesets {
+		if cs.DryRun {
+			s.logChangeset(cs)
+			settleSnapshot(plan.Snapshot, journal.Snapshot, cs, false)
+			continue
+		}
+		target, ok := s.targets[cs.Provider]
+		if !ok {
+			err := fmt.Errorf("no target configured for changeset provider %s", cs.Provider)
+			s.logger.Error("failure applying changeset", "provider", cs.Provider, "resource", cs.Resource, "name", cs.ListName, logger.Error(err))
+			return err
+		}
+		for _, step := range cs.steps() {
+			// the trakt list might only have been created by the previous step
+			step.TraktListID = cmp.Or(step.TraktListID, cs.TraktListID)
+			if err := target.Apply(ctx, &step); err != nil {
+				s.logger.Error("failure applying changeset", "provider", cs.Provider, "resource", cs.Resource, "name", cs.ListName, logger.Error(err))
+				return err
+			}
+			cs.TraktListID = step.TraktListID
+			cs.NotFound = append(cs.NotFound, step.NotFound...)
+			if st != nil {
+				persistNotFound(st, step)
+			}
+			// undoing a sync marks the journal as undone instead
+			if plan.Undo || !journal.add(step) {
+				continue
+			}
+			if err := journal.WriteFile(*s.conf.JournalFile); err != nil {
+				s.logger.Error("failure writing journal", logger.Error(err))
+				return err
+			}
+		}
+		settleSnapshot(plan.Snapshot, journal.Snapshot, cs, true)
+	}
+	if st != nil {
+		if plan.Snapshot != nil {
+			st.Snapshot = plan.Snapshot
+		}
+		if s.caches() {
+			st.Cache = s.cache
+		}
+		if err := st.Save(*s.conf.StateFile); err != nil {
+			s.logger.Error("failure saving sync state", logger.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// backup archives the trakt data before a full sync changes it, so that it can
+// be restored in case the sync went wrong.
+func (s *Syncer) backup(ctx context.Context, plan *Plan) error {
+	if !*s.conf.Backup || *s.conf.Mode != appconfig.SyncModeFull || !plan.changesTrakt() {
+		return nil
+	}
+	archive, err := backup.Create(ctx, s.traktClient, s.logger)
+	if err != nil {
 		return err
 	}
-	if err := s.syncHistory(ctx); err != nil {
-		s.logger.Error("failure syncing history", logger.Error(err))
+	path, err := archive.WriteDir(*s.conf.BackupDir)
+	if err != nil {
 		return err
 	}
-	s.logger.Info("sync completed")
+	s.logger.Info("saved trakt backup", "path", path)
 	return nil
 }
 
-func (s *Syncer) setupTraktLists(ctx context.Context, imdbLists imdb.Lists) (trakt.IDMetas, error) {
+func (s *Syncer) setupTraktLists(ctx context.Context, imdbLists imdb.Lists) (trakt.Lists, error) {
 	traktLists, err := s.traktClient.ListsGetAllMeta(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failure fetching trakt lists metadata: %w", err)
 	}
-	traktListsMetaMap := make(map[string]trakt.IDMeta, len(traktLists))
+	traktListsMetaMap := make(map[string]trakt.List, len(traktLists))
 	for _, traktList := range traktLists {
-		traktListsMetaMap[*traktList.Name] = traktList.IDMeta
+		traktListsMetaMap[*traktList.Name] = traktList
 	}
 
-	traktListsMeta := make(trakt.IDMetas, 0, len(imdbLists))
+	traktListsMeta := make(trakt.Lists, 0, len(imdbLists))
 	for _, imdbList := range imdbLists {
-		s.user.imdbLists[imdbList.ListID] = imdbList
-		if *s.conf.Mode == appconfig.SyncModeDryRun {
-			s.logger.Info("sync would have created trakt list", "name", imdbList.ListName)
-			continue
-		}
 		traktListMeta, ok := traktListsMetaMap[imdbList.ListName]
 		if !ok {
-			newTraktListMeta, err := s.traktClient.ListCreate(ctx, imdbList.ListName)
-			if err != nil {
-				return nil, fmt.Errorf("failure creating trakt list %s: %w", imdbList.ListName, err)
-			}
-			traktListMeta = *newTraktListMeta
+			// the list gets created when the plan is applied
+			continue
 		}
-		traktListMeta.IMDb = imdbList.ListID
+		traktListMeta.IDMeta.IMDb = imdbList.ListID
 		traktListsMeta = append(traktListsMeta, traktListMeta)
 	}
 
@@ -119,67 +315,95 @@ func (s *Syncer) hydrate(ctx context.Context) error {
 	for lid := range s.user.imdbLists {
 		lids = append(lids, lid)
 	}
-	if *s.conf.Ratings {
+	// the configured list ids only serve as placeholders until the actual lists are fetched
+	clear(s.user.imdbLists)
+	if err := s.fetchActivities(ctx); err != nil {
+		return err
+	}
+	if s.fetches(ResourceRatings) {
 		if err := s.imdbClient.RatingsExport(); err != nil {
 			return fmt.Errorf("failure exporting imdb ratings: %w", err)
 		}
 	}
-	if *s.conf.Lists {
+	if s.fetches(ResourceList) {
 		if err := s.imdbClient.ListsExport(lids...); err != nil {
 			return fmt.Errorf("failure exporting imdb lists: %w", err)
 		}
 	}
-	if *s.conf.Watchlist {
+	if s.fetches(ResourceWatchlist) {
 		if err := s.imdbClient.WatchlistExport(); err != nil {
 			return fmt.Errorf("failure exporting imdb watchlist: %w", err)
 		}
 	}
-	if *s.conf.Lists {
+	if s.fetches(ResourceList) {
 		imdbLists, err := s.imdbClient.ListsGet(lids...)
 		if err != nil {
 			return fmt.Errorf("failure fetching imdb lists: %w", err)
 		}
-		traktIDMetas, err := s.setupTraktLists(ctx, imdbLists)
-		if err != nil {
-			return fmt.Errorf("failure setting up trakt lists: %w", err)
-		}
-		traktLists, err := s.traktClient.ListsGet(ctx, traktIDMetas)
-		if err != nil {
-			return fmt.Errorf("failure hydrating trakt lists: %w", err)
+		for _, imdbList := range imdbLists {
+			s.user.imdbLists[imdbList.ListID] = imdbList
 		}
-		for _, traktList := range traktLists {
-			s.user.traktLists[traktList.IDMeta.IMDb] = traktList
+		if *s.conf.Lists {
+			traktListsMeta, err := s.setupTraktLists(ctx, imdbLists)
+			if err != nil {
+				return fmt.Errorf("failure setting up trakt lists: %w", err)
+			}
+			traktLists, err := s.fetchTraktLists(ctx, traktListsMeta)
+			if err != nil {
+				return fmt.Errorf("failure hydrating trakt lists: %w", err)
+			}
+			for _, traktList := range traktLists {
+				if traktList.Name != nil {
+					s.reportSeasons(*traktList.Name, traktList.ListItems)
+				}
+				traktList.ListItems = s.resolver.imdbIDs(traktList.ListItems)
+				s.user.traktLists[traktList.IDMeta.IMDb] = traktList
+			}
 		}
 	}
 	if s.authless {
 		return nil
 	}
-	if *s.conf.Watchlist {
+	if s.fetches(ResourceWatchlist) {
 		imdbWatchlist, err := s.imdbClient.WatchlistGet()
 		if err != nil {
 			return fmt.Errorf("failure fetching imdb watchlist: %w", err)
 		}
 		s.user.imdbLists[imdbWatchlist.ListID] = *imdbWatchlist
-		traktWatchlist, err := s.traktClient.WatchlistGet(ctx)
-		if err != nil {
-			return fmt.Errorf("failure fetching trakt watchlist: %w", err)
+		if *s.conf.Watchlist {
+			traktWatchlist, err := s.fetchTraktWatchlist(ctx)
+			if err != nil {
+				return fmt.Errorf("failure fetching trakt watchlist: %w", err)
+			}
+			watchlist := *traktWatchlist
+			s.reportSeasons(string(ResourceWatchlist), watchlist.ListItems)
+			watchlist.ListItems = s.resolver.imdbIDs(watchlist.ListItems)
+			s.user.traktLists[imdbWatchlist.ListID] = watchlist
 		}
-		s.user.traktLists[imdbWatchlist.ListID] = *traktWatchlist
 	}
 	if *s.conf.Ratings {
-		traktRatings, err := s.traktClient.RatingsGet(ctx)
+		traktRatings, err := s.fetchTraktRatings(ctx)
 		if err != nil {
 			return fmt.Errorf("failure fetching trakt ratings: %w", err)
 		}
+		s.reportSeasons(string(ResourceRatings), traktRatings)
+		traktRatings = s.resolver.imdbIDs(traktRatings)
 		for _, traktRating := range traktRatings {
 			id, err := traktRating.GetItemID()
 			if err != nil {
 				return fmt.Errorf("failure fetching trakt item id: %w", err)
 			}
-			if id != nil {
+			switch {
+			case id == nil:
+				// seasons have been reported already
+			case *id == "":
+				s.logger.Info("skipping trakt rating without imdb id", "type", traktRating.Type, "title", traktRating.ToMedia().Title)
+			default:
 				s.user.traktRatings[*id] = traktRating
 			}
 		}
+	}
+	if s.fetches(ResourceRatings) {
 		imdbRatings, err := s.imdbClient.RatingsGet()
 		if err != nil {
 			return fmt.Errorf("failure fetching imdb ratings: %w", err)
@@ -191,7 +415,7 @@ func (s *Syncer) hydrate(ctx context.Context) error {
 	return nil
 }
 
-func (s *Syncer) syncLists(ctx context.Context) error {
+func (s *Syncer) planLists(plan *Plan) {
 	if !*s.conf.Watchlist {
 		s.logger.Info("skipping watchlist sync")
 	}
@@ -199,98 +423,58 @@ func (s *Syncer) syncLists(ctx context.Context) error {
 		s.logger.Info("skipping lists sync")
 	}
 	if !*s.conf.Watchlist && !*s.conf.Lists {
-		return nil
+		return
 	}
-	for _, imdbList := range s.user.imdbLists {
-		diff := listDiff(imdbList, s.user.traktLists[imdbList.ListID])
-		if imdbList.IsWatchlist {
-			if len(diff.Add) > 0 {
-				if *s.conf.Mode == appconfig.SyncModeDryRun {
-					s.logger.Info("sync would have added trakt watchlist items", "count", len(diff.Add))
-					continue
-				}
-				if err := s.traktClient.WatchlistItemsAdd(ctx, diff.Add); err != nil {
-					return fmt.Errorf("failure adding items to trakt watchlist: %w", err)
-				}
-			} else {
-				s.logger.Info("no trakt watchlist items to add")
-			}
-			if len(diff.Remove) > 0 {
-				if *s.conf.Mode == appconfig.SyncModeDryRun || *s.conf.Mode == appconfig.SyncModeAddOnly {
-					s.logger.Info("sync would have removed trakt watchlist items", "count", len(diff.Remove))
-					continue
-				}
-				if err := s.traktClient.WatchlistItemsRemove(ctx, diff.Remove); err != nil {
-					return fmt.Errorf("failure removing trakt watchlist items: %w", err)
-				}
-			} else {
-				s.logger.Info("no trakt watchlist items to remove")
-			}
+	for _, lid := range slices.Sorted(maps.Keys(s.user.imdbLists)) {
+		imdbList := s.user.imdbLists[lid]
+		if !s.listInScope(imdbList) {
 			continue
 		}
-		traktListID := s.user.traktLists[imdbList.ListID].IDMeta.Trakt
-		if len(diff.Add) > 0 {
-			if *s.conf.Mode == appconfig.SyncModeDryRun {
-				s.logger.Info("sync would have added trakt list items", "count", len(diff.Add), "name", imdbList.ListName)
-				continue
-			}
-			if err := s.traktClient.ListItemsAdd(ctx, traktListID, imdbList.ListName, diff.Add); err != nil {
-				return fmt.Errorf("failure adding items to trakt list %s: %w", imdbList.ListName, err)
-			}
-		} else {
-			s.logger.Info("no trakt list items to add", "name", imdbList.ListName)
-		}
-		if len(diff.Remove) > 0 {
-			if *s.conf.Mode == appconfig.SyncModeDryRun || *s.conf.Mode == appconfig.SyncModeAddOnly {
-				s.logger.Info("sync would have deleted trakt list items", "count", len(diff.Remove), "name", imdbList.ListName)
-				continue
-			}
-			if err := s.traktClient.ListItemsRemove(ctx, traktListID, imdbList.ListName, diff.Remove); err != nil {
-				return fmt.Errorf("failure removing trakt list items from %s: %w", imdbList.ListName, err)
-			}
-		} else {
-			s.logger.Info("no trakt list items to remove", "name", imdbList.ListName)
+		traktList := s.user.traktLists[lid]
+		diff := itemsDifference(media.ByID(imdbList.ListItems), media.ByID(traktList.ListItems), ProviderIMDb, ProviderTrakt)
+		if imdbList.IsWatchlist {
+			s.addChangeset(plan, Changeset{
+				Provider: ProviderTrakt,
+				Resource: ResourceWatchlist,
+				Add:      diff.Add,
+				Remove:   diff.Remove,
+				Reasons:  diff.Reasons,
+			})
+			continue
 		}
+		s.addChangeset(plan, Changeset{
+			Provider:    ProviderTrakt,
+			Resource:    ResourceList,
+			ListID:      imdbList.ListID,
+			ListName:    imdbList.ListName,
+			TraktListID: traktList.IDMeta.Trakt,
+			Add:         diff.Add,
+			Remove:      diff.Remove,
+			Reasons:     diff.Reasons,
+		})
 	}
-	return nil
 }
 
-func (s *Syncer) syncRatings(ctx context.Context) error {
+func (s *Syncer) planRatings(plan *Plan) {
 	if s.authless {
 		s.logger.Info("skipping ratings sync since no imdb auth was provided")
-		return nil
+		return
 	}
 	if !*s.conf.Ratings {
 		s.logger.Info("skipping ratings sync")
-		return nil
-	}
-	diff := itemsDifference(s.user.imdbRatings, s.user.traktRatings)
-	if len(diff.Add) > 0 {
-		if *s.conf.Mode == appconfig.SyncModeDryRun {
-			s.logger.Info("sync would have added trakt ratings", "count", len(diff.Add))
-		} else {
-			if err := s.traktClient.RatingsAdd(ctx, diff.Add); err != nil {
-				return fmt.Errorf("failure adding trakt ratings: %w", err)
-			}
-		}
-	} else {
-		s.logger.Info("no trakt ratings to add")
-	}
-	if len(diff.Remove) > 0 {
-		if *s.conf.Mode == appconfig.SyncModeDryRun || *s.conf.Mode == appconfig.SyncModeAddOnly {
-			s.logger.Info("sync would have deleted trakt ratings", "count", len(diff.Remove))
-		} else {
-			if err := s.traktClient.RatingsRemove(ctx, diff.Remove); err != nil {
-				return fmt.Errorf("failure removing trakt ratings: %w", err)
-			}
-		}
-	} else {
-		s.logger.Info("no trakt ratings to remove")
-	}
-	return nil
+		return
+	}
+	diff := itemsDifference(convertRatings(toMedia(s.user.imdbRatings), s.ratings.ToTrakt), toMedia(s.user.traktRatings), ProviderIMDb, ProviderTrakt)
+	s.addChangeset(plan, Changeset{
+		Provider: ProviderTrakt,
+		Resource: ResourceRatings,
+		Add:      diff.Add,
+		Remove:   diff.Remove,
+		Reasons:  diff.Reasons,
+	})
 }
 
-func (s *Syncer) syncHistory(ctx context.Context) error {
+func (s *Syncer) planHistory(ctx context.Context, plan *Plan) error {
 	if s.authless {
 		s.logger.Info("skipping history sync since no imdb auth was provided")
 		return nil
@@ -302,62 +486,124 @@ func (s *Syncer) syncHistory(ctx context.Context) error {
 	// imdb doesn't offer functionality similar to trakt history, hence why there can't be a direct mapping between them
 	// the syncer will assume a user to have watched an item if they've submitted a rating for it
 	// if the above is satisfied and the user's history for this item is empty, a new history entry is added!
-	diff := itemsDifference(s.user.imdbRatings, s.user.traktRatings)
-	if len(diff.Add) > 0 {
-		var historyToAdd trakt.Items
-		for i := range diff.Add {
-			traktItemID, err := diff.Add[i].GetItemID()
-			if err != nil {
-				return fmt.Errorf("failure fetching trakt item id: %w", err)
-			}
-			history, err := s.traktClient.HistoryGet(ctx, diff.Add[i].Type, *traktItemID)
-			if err != nil {
-				return fmt.Errorf("failure fetching trakt history for %s %s: %w", diff.Add[i].Type, *traktItemID, err)
-			}
-			if len(history) > 0 {
-				continue
-			}
-			historyToAdd = append(historyToAdd, diff.Add[i])
-		}
-		if len(historyToAdd) > 0 {
-			if *s.conf.Mode == appconfig.SyncModeDryRun {
-				s.logger.Info("sync would have added trakt history", "count", len(historyToAdd))
-			} else {
-				if err := s.traktClient.HistoryAdd(ctx, historyToAdd); err != nil {
-					return fmt.Errorf("failure adding trakt history: %w", err)
-				}
-			}
+	diff := itemsDifference(convertRatings(toMedia(s.user.imdbRatings), s.ratings.ToTrakt), toMedia(s.user.traktRatings), ProviderIMDb, ProviderTrakt)
+	historyGet := s.traktHistory()
+	historyToAdd := make(media.Items, 0, len(diff.Add))
+	for _, it := range diff.Add {
+		history, err := historyGet(ctx, it)
+		if err != nil {
+			return fmt.Errorf("failure fetching trakt history for %s %s: %w", it.Type, it.IDs.IMDb, err)
+		}
+		switch {
+		case len(history) == 0:
+			it.WatchedAt = s.watchedAt(it)
+			diff.Reasons[it.IDs.IMDb] = reasonMissingInTraktHistory
+		case s.rewatched(it, history):
+			// the play is added at the rated date, since any other date would
+			// duplicate the play that is already in trakt history
+			diff.Reasons[it.IDs.IMDb] = reasonRewatchedOnIMDb
+		default:
+			continue
 		}
-	} else {
-		s.logger.Info("no history to add to trakt")
+		historyToAdd = append(historyToAdd, it)
 	}
-	if len(diff.Remove) > 0 {
-		var historyToRemove trakt.Items
-		for i := range diff.Remove {
-			traktItemID, err := diff.Remove[i].GetItemID()
-			if err != nil {
-				return fmt.Errorf("failure fetching trakt item id: %w", err)
-			}
-			history, err := s.traktClient.HistoryGet(ctx, diff.Remove[i].Type, *traktItemID)
-			if err != nil {
-				return fmt.Errorf("failure fetching trakt history for %s %s: %w", diff.Remove[i].Type, *traktItemID, err)
+	historyToRemove := make(media.Items, 0, len(diff.Remove))
+	removedPlays := make(media.Items, 0)
+	removed := make(map[int64]struct{})
+	for _, it := range diff.Remove {
+		history, err := historyGet(ctx, it)
+		if err != nil {
+			return fmt.Errorf("failure fetching trakt history for %s %s: %w", it.Type, it.IDs.IMDb, err)
+		}
+		if len(history) == 0 {
+			continue
+		}
+		historyToRemove = append(historyToRemove, it)
+		for _, play := range history {
+			// a show is removed together with the plays of its episodes
+			if _, ok := removed[play.ID]; !ok {
+				removed[play.ID] = struct{}{}
+				removedPlays = append(removedPlays, play.ToMedia())
 			}
-			if len(history) == 0 {
-				continue
+		}
+	}
+	s.addChangeset(plan, Changeset{
+		Provider:     ProviderTrakt,
+		Resource:     ResourceHistory,
+		Add:          historyToAdd,
+		Remove:       historyToRemove,
+		Reasons:      diff.Reasons,
+		RemovedPlays: removedPlays,
+		LastPlayID:   lastPlayID(s.user.traktHistory),
+	})
+	return nil
+}
+
+// addChangeset adds cs to the plan, honouring the mode of its target. Removals
+// are left out in add-only mode, so that applying the plan later can never
+// delete anything, and targets in dry-run mode only report their changes,
+// unless the whole sync is a dry run anyway. Ratings that get overwritten are
+// kept with the changeset, so that they can be restored on undo.
+func (s *Syncer) addChangeset(plan *Plan, cs Changeset) {
+	mode := s.scopes[cs.Provider].Mode
+	if mode == appconfig.SyncModeAddOnly && len(cs.Remove) > 0 {
+		s.logger.Info("sync would have removed items", "provider", cs.Provider, "target", cs.target(), "count", len(cs.Remove))
+		cs.Remove = nil
+	}
+	cs.DryRun = mode == appconfig.SyncModeDryRun && *s.conf.Mode != appconfig.SyncModeDryRun
+	if cs.Provider == ProviderTrakt {
+		s.skipNotFound(&cs)
+	}
+	if cs.Resource == ResourceRatings {
+		cs.Previous = s.previousRatings(cs)
+	}
+	plan.add(cs)
+}
+
+func (s *Syncer) previousRatings(cs Changeset) media.Items {
+	previous := make(media.Items, 0)
+	for _, it := range cs.Add {
+		id := it.IDs.IMDb
+		switch cs.Provider {
+		case ProviderTrakt:
+			if traktItem, ok := s.user.traktRatings[id]; ok {
+				previous = append(previous, traktItem.ToMedia())
 			}
-			historyToRemove = append(historyToRemove, diff.Remove[i])
-		}
-		if len(historyToRemove) > 0 {
-			if *s.conf.Mode == appconfig.SyncModeDryRun || *s.conf.Mode == appconfig.SyncModeAddOnly {
-				s.logger.Info("sync would have deleted trakt history", "count", len(historyToRemove))
-			} else {
-				if err := s.traktClient.HistoryRemove(ctx, historyToRemove); err != nil {
-					return fmt.Errorf("failure removing trakt history: %w", err)
-				}
+		case ProviderIMDb:
+			if imdbItem, ok := s.user.imdbRatings[id]; ok {
+				previous = append(previous, imdbItem.ToMedia())
 			}
 		}
-	} else {
-		s.logger.Info("no trakt history to remove")
 	}
-	return nil
+	return previous
+}
+
+// listInScope reports whether imdbList is synced between imdb and trakt, since
+// lists are also fetched for other targets.
+func (s *Syncer) listInScope(imdbList imdb.List) bool {
+	if imdbList.IsWatchlist {
+		return *s.conf.Watchlist
+	}
+	return *s.conf.Lists
+}
+
+func (s *Syncer) logPlan(plan *Plan) {
+	for _, cs := range plan.Changesets {
+		s.logChangeset(cs)
+	}
+}
+
+func (s *Syncer) logChangeset(cs Changeset) {
+	if cs.Provider == ProviderTrakt && cs.Resource == ResourceList && cs.TraktListID == 0 {
+		s.logger.Info("sync would have created trakt list", "name", cs.ListName)
+	}
+	for _, it := range cs.Add {
+		s.logger.Info("sync would have added item", cs.reportAttrs(it)...)
+	}
+	for _, it := range cs.Remove {
+		s.logger.Info("sync would have removed item", cs.reportAttrs(it)...)
+	}
+	if len(cs.Add) > 0 || len(cs.Remove) > 0 {
+		s.logger.Info("sync would have changed items", "provider", cs.Provider, "target", cs.target(), "added", len(cs.Add), "removed", len(cs.Remove))
+	}
 }
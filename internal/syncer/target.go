@@ -0,0 +1,363 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	appconfig "github.com/cecobask/imdb-trakt-sync/internal/config"
+	"github.com/cecobask/imdb-trakt-sync/internal/imdb"
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
+	"github.com/cecobask/imdb-trakt-sync/internal/trakt"
+)
+
+// Target is a destination the changesets of a plan are applied to. Every
+// target handles the changesets of a single provider.
+type Target interface {
+	// Supports reports whether the target can hold the given resource.
+	Supports(r Resource) bool
+	Apply(ctx context.Context, cs *Changeset) error
+}
+
+// snapshotTarget is a target that can't be read from, e.g. files that have to
+// be imported by hand. Rather than a diff, it is handed every imdb item of each
+// resource in its scope that it accepts.
+type snapshotTarget interface {
+	Target
+	accepts(it media.Item) bool
+}
+
+// Scope is the mode and the resources a target is synced with.
+type Scope struct {
+	Mode      appconfig.SyncMode
+	History   bool
+	Ratings   bool
+	Watchlist bool
+	Lists     bool
+}
+
+func (sc Scope) includes(r Resource) bool {
+	switch r {
+	case ResourceHistory:
+		return sc.History
+	case ResourceRatings:
+		return sc.Ratings
+	case ResourceWatchlist:
+		return sc.Watchlist
+	case ResourceList:
+		return sc.Lists
+	default:
+		return false
+	}
+}
+
+// newScopes returns the scope of every target. Trakt and imdb are synced with
+// the sync settings, while every other target can have its own.
+func newScopes(conf *appconfig.Config) map[Provider]Scope {
+	sync := Scope{
+		Mode:      *conf.Sync.Mode,
+		History:   *conf.Sync.History,
+		Ratings:   *conf.Sync.Ratings,
+		Watchlist: *conf.Sync.Watchlist,
+		Lists:     *conf.Sync.Lists,
+	}
+	return map[Provider]Scope{
+		ProviderTrakt:      sync,
+		ProviderIMDb:       sync,
+		ProviderLetterboxd: targetScope(conf.Letterboxd.Target),
+	}
+}
+
+func targetScope(target appconfig.Target) Scope {
+	return Scope{
+		Mode:      *target.Mode,
+		Ratings:   *target.Ratings,
+		Watchlist: *target.Watchlist,
+		Lists:     *target.Lists,
+	}
+}
+
+func newTargets(conf *appconfig.Config, logger *slog.Logger, traktClient trakt.API, imdbClient imdb.API, resolver *resolver) map[Provider]Target {
+	targets := map[Provider]Target{
+		ProviderTrakt: &traktTarget{
+			client:   traktClient,
+			logger:   logger,
+			resolver: resolver,
+		},
+		ProviderIMDb: &imdbTarget{
+			client: imdbClient,
+			logger: logger,
+		},
+	}
+	if *conf.Letterboxd.Directory != "" {
+		targets[ProviderLetterboxd] = &letterboxdTarget{
+			directory: *conf.Letterboxd.Directory,
+			logger:    logger,
+		}
+	}
+	return targets
+}
+
+type traktTarget struct {
+	client   trakt.API
+	logger   *slog.Logger
+	resolver *resolver
+}
+
+func (t *traktTarget) Supports(_ Resource) bool {
+	return true
+}
+
+func (t *traktTarget) Apply(ctx context.Context, cs *Changeset) error {
+	switch cs.Resource {
+	case ResourceWatchlist:
+		return t.applyWatchlist(ctx, cs)
+	case ResourceList:
+		return t.applyList(ctx, cs)
+	case ResourceRatings:
+		return t.applyRatings(ctx, cs)
+	case ResourceHistory:
+		return t.applyHistory(ctx, cs)
+	default:
+		return fmt.Errorf("unknown changeset resource %s", cs.Resource)
+	}
+}
+
+func (t *traktTarget) applyWatchlist(ctx context.Context, cs *Changeset) error {
+	if len(cs.Add) > 0 {
+		if err := t.add(ctx, cs, t.client.WatchlistItemsAdd); err != nil {
+			return fmt.Errorf("failure adding items to trakt watchlist: %w", err)
+		}
+	} else {
+		t.logger.Info("no trakt watchlist items to add")
+	}
+	if len(cs.Remove) > 0 {
+		if err := t.client.WatchlistItemsRemove(ctx, trakt.NewItemsFromMedia(cs.Remove)); err != nil {
+			return fmt.Errorf("failure removing trakt watchlist items: %w", err)
+		}
+	} else {
+		t.logger.Info("no trakt watchlist items to remove")
+	}
+	return nil
+}
+
+// applyList records the id of the trakt list in cs, since the list might
+// only have been created now and undoing the changes has to target it.
+func (t *traktTarget) applyList(ctx context.Context, cs *Changeset) error {
+	traktListID, err := t.traktListID(ctx, *cs)
+	if err != nil {
+		return fmt.Errorf("failure setting up trakt list %s: %w", cs.ListName, err)
+	}
+	cs.TraktListID = traktListID
+	if len(cs.Add) > 0 {
+		addFunc := func(ctx context.Context, its trakt.Items) (trakt.Items, error) {
+			return t.client.ListItemsAdd(ctx, traktListID, cs.ListName, its)
+		}
+		if err = t.add(ctx, cs, addFunc); err != nil {
+			return fmt.Errorf("failure adding items to trakt list %s: %w", cs.ListName, err)
+		}
+	} else {
+		t.logger.Info("no trakt list items to add", "name", cs.ListName)
+	}
+	if len(cs.Remove) > 0 {
+		if err = t.client.ListItemsRemove(ctx, traktListID, cs.ListName, trakt.NewItemsFromMedia(cs.Remove)); err != nil {
+			return fmt.Errorf("failure removing trakt list items from %s: %w", cs.ListName, err)
+		}
+	} else {
+		t.logger.Info("no trakt list items to remove", "name", cs.ListName)
+	}
+	return nil
+}
+
+// add sends the added items of cs to trakt through addFunc. The items trakt
+// could not find are resolved on their other ids and sent once more, and the
+// ones that are still missing after that are recorded in cs.
+func (t *traktTarget) add(ctx context.Context, cs *Changeset, addFunc func(context.Context, trakt.Items) (trakt.Items, error)) error {
+	notFound, err := addFunc(ctx, trakt.NewItemsFromMedia(cs.Add))
+	if err != nil {
+		return err
+	}
+	missing := notFoundIDs(notFound)
+	if len(missing) > 0 && t.resolver != nil {
+		unresolved := make([]*media.Item, 0, len(missing))
+		for i := range cs.Add {
+			if _, ok := missing[cs.Add[i].IDs.IMDb]; ok {
+				unresolved = append(unresolved, &cs.Add[i])
+			}
+		}
+		if resolved := t.resolver.resolve(ctx, unresolved); len(resolved) > 0 {
+			retry := make(media.Items, 0, len(resolved))
+			for _, it := range resolved {
+				retry = append(retry, *it)
+				delete(missing, it.IDs.IMDb)
+			}
+			if notFound, err = addFunc(ctx, trakt.NewItemsFromMedia(retry)); err != nil {
+				return err
+			}
+			maps.Copy(missing, notFoundIDs(notFound))
+			t.resolver.remember(resolved, missing)
+		}
+	}
+	t.recordNotFound(cs, missing)
+	return nil
+}
+
+func notFoundIDs(notFound trakt.Items) map[string]struct{} {
+	ids := make(map[string]struct{}, len(notFound))
+	for _, it := range notFound {
+		ids[it.ToMedia().IDs.IMDb] = struct{}{}
+	}
+	return ids
+}
+
+// recordNotFound keeps the added items that trakt could not match in cs, so
+// that they can be persisted and skipped by the following syncs.
+func (t *traktTarget) recordNotFound(cs *Changeset, missing map[string]struct{}) {
+	cs.NotFound = nil
+	for _, it := range cs.Add {
+		if _, ok := missing[it.IDs.IMDb]; !ok {
+			continue
+		}
+		cs.NotFound = append(cs.NotFound, it)
+		t.logger.Warn("trakt could not find item", "imdbID", it.IDs.IMDb, "type", it.Type, "title", it.Title, "target", cs.target())
+	}
+}
+
+// traktListID resolves the trakt list a changeset targets. Lists that did not
+// exist at planning time are looked up by name again, in case they have been
+// created since, and are only created when they are still missing.
+func (t *traktTarget) traktListID(ctx context.Context, cs Changeset) (int, error) {
+	if cs.TraktListID != 0 {
+		return cs.TraktListID, nil
+	}
+	traktLists, err := t.client.ListsGetAllMeta(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failure fetching trakt lists metadata: %w", err)
+	}
+	for _, traktList := range traktLists {
+		if *traktList.Name == cs.ListName {
+			return traktList.IDMeta.Trakt, nil
+		}
+	}
+	idMeta, err := t.client.ListCreate(ctx, trakt.List{Name: &cs.ListName})
+	if err != nil {
+		return 0, fmt.Errorf("failure creating trakt list: %w", err)
+	}
+	return idMeta.Trakt, nil
+}
+
+func (t *traktTarget) applyRatings(ctx context.Context, cs *Changeset) error {
+	if len(cs.Add) > 0 {
+		if err := t.add(ctx, cs, t.client.RatingsAdd); err != nil {
+			return fmt.Errorf("failure adding trakt ratings: %w", err)
+		}
+	} else {
+		t.logger.Info("no trakt ratings to add")
+	}
+	if len(cs.Remove) > 0 {
+		if err := t.client.RatingsRemove(ctx, trakt.NewItemsFromMedia(cs.Remove)); err != nil {
+			return fmt.Errorf("failure removing trakt ratings: %w", err)
+		}
+	} else {
+		t.logger.Info("no trakt ratings to remove")
+	}
+	return nil
+}
+
+func (t *traktTarget) applyHistory(ctx context.Context, cs *Changeset) error {
+	if len(cs.Add) > 0 {
+		if err := t.add(ctx, cs, t.client.HistoryAdd); err != nil {
+			return fmt.Errorf("failure adding trakt history: %w", err)
+		}
+	} else {
+		t.logger.Info("no history to add to trakt")
+	}
+	if len(cs.Remove) > 0 {
+		if err := t.client.HistoryRemove(ctx, trakt.NewItemsFromMedia(cs.Remove)); err != nil {
+			return fmt.Errorf("failure removing trakt history: %w", err)
+		}
+	} else {
+		t.logger.Info("no trakt history to remove")
+	}
+	return nil
+}
+
+const reasonSnapshot = "part of the full snapshot of the imdb data"
+
+// planSnapshotTargets hands every snapshot target all imdb items of the
+// resources in its scope.
+func (s *Syncer) planSnapshotTargets(plan *Plan) {
+	for _, provider := range slices.Sorted(maps.Keys(s.targets)) {
+		target, ok := s.targets[provider].(snapshotTarget)
+		if !ok {
+			continue
+		}
+		for _, lid := range slices.Sorted(maps.Keys(s.user.imdbLists)) {
+			imdbList := s.user.imdbLists[lid]
+			cs := Changeset{
+				Provider: provider,
+				Resource: ResourceList,
+				ListID:   imdbList.ListID,
+				ListName: imdbList.ListName,
+			}
+			if imdbList.IsWatchlist {
+				cs.Resource, cs.ListID, cs.ListName = ResourceWatchlist, "", ""
+			}
+			if !s.inScope(provider, cs.Resource) {
+				continue
+			}
+			cs.Add, cs.Reasons = snapshotItems(target, imdbList.ListItems, s.resolver)
+			s.addChangeset(plan, cs)
+		}
+		if s.authless || !s.inScope(provider, ResourceRatings) {
+			continue
+		}
+		ratings := make(imdb.Items, 0, len(s.user.imdbRatings))
+		for _, id := range slices.Sorted(maps.Keys(s.user.imdbRatings)) {
+			ratings = append(ratings, s.user.imdbRatings[id])
+		}
+		cs := Changeset{
+			Provider: provider,
+			Resource: ResourceRatings,
+		}
+		cs.Add, cs.Reasons = snapshotItems(target, ratings, s.resolver)
+		s.addChangeset(plan, cs)
+	}
+}
+
+// snapshotItems applies the known mappings before checking whether target
+// accepts an item, since they correct the type of mis-typed imdb titles.
+func snapshotItems(target snapshotTarget, items imdb.Items, r *resolver) (media.Items, map[string]string) {
+	its := make(media.Items, 0, len(items))
+	reasons := make(map[string]string, len(items))
+	for _, item := range items {
+		it := item.ToMedia()
+		if m, ok := r.lookup(it.IDs.IMDb); ok {
+			m.Apply(&it)
+		}
+		if !target.accepts(it) {
+			continue
+		}
+		its = append(its, it)
+		reasons[item.ID] = reasonSnapshot
+	}
+	return its, reasons
+}
+
+// inScope reports whether r is synced to the target of provider.
+func (s *Syncer) inScope(provider Provider, r Resource) bool {
+	target, ok := s.targets[provider]
+	return ok && target.Supports(r) && s.scopes[provider].includes(r)
+}
+
+// fetches reports whether the imdb data of r is needed by any target.
+func (s *Syncer) fetches(r Resource) bool {
+	for provider := range s.targets {
+		if s.inScope(provider, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -22,8 +23,10 @@ const (
 	pathHistory             = "/sync/history"
 	pathHistoryGet          = "/sync/history/%s/%s"
 	pathHistoryRemove       = "/sync/history/remove"
+	pathLastActivities      = "/sync/last_activities"
 	pathRatings             = "/sync/ratings"
 	pathRatingsRemove       = "/sync/ratings/remove"
+	pathSearch              = "/search/%s/%s"
 	pathUserInfo            = "/users/me"
 	pathUserList            = "/users/%s/lists/%d"
 	pathUserListItems       = "/users/%s/lists/%d/items"
@@ -38,36 +41,43 @@ const (
 	// very large payload (thousands of items), so large item sets are split
 	// into multiple requests instead of being sent in one shot.
 	bulkRequestChunkSize = 200
+
+	headerPaginationPageCount = "X-Pagination-Page-Count"
+	paginationLimit           = 1000
 )
 
 type client struct {
-	baseURL    string
-	httpClient *http.Client
-	logger     *slog.Logger
-	username   string
+	baseURL         string
+	httpClient      *http.Client
+	logger          *slog.Logger
+	username        string
+	listParallelism int
 }
 
 type API interface {
-	HistoryAdd(ctx context.Context, its Items) error
+	HistoryAdd(ctx context.Context, its Items) (Items, error)
 	HistoryGet(ctx context.Context, itType, itID string) (Items, error)
+	HistoryGetAll(ctx context.Context) (Items, error)
 	HistoryRemove(ctx context.Context, its Items) error
-	ListCreate(ctx context.Context, name string) (*IDMeta, error)
+	LastActivitiesGet(ctx context.Context) (*LastActivities, error)
+	ListCreate(ctx context.Context, list List) (*IDMeta, error)
 	ListGet(ctx context.Context, lid int) (*List, error)
 	ListGetMeta(ctx context.Context, lid int) (*List, error)
-	ListItemsAdd(ctx context.Context, lid int, name string, its Items) error
+	ListItemsAdd(ctx context.Context, lid int, name string, its Items) (Items, error)
 	ListItemsRemove(ctx context.Context, lid int, name string, its Items) error
 	ListsGet(ctx context.Context, ids IDMetas) (Lists, error)
 	ListsGetAllMeta(ctx context.Context) (Lists, error)
-	RatingsAdd(ctx context.Context, its Items) error
+	RatingsAdd(ctx context.Context, its Items) (Items, error)
 	RatingsGet(ctx context.Context) (Items, error)
 	RatingsRemove(ctx context.Context, its Items) error
+	Search(ctx context.Context, idType, id, itType string) (Items, error)
 	WatchlistGet(ctx context.Context) (*List, error)
-	WatchlistItemsAdd(ctx context.Context, its Items) error
+	WatchlistItemsAdd(ctx context.Context, its Items) (Items, error)
 	WatchlistItemsRemove(ctx context.Context, its Items) error
 }
 
 func NewAPI(ctx context.Context, conf config.Trakt, logger *slog.Logger) (API, error) {
-	retryTrans := newRetryTransport(http.DefaultTransport, logger)
+	retryTrans := newRetryTransport(newRateLimitTransport(http.DefaultTransport), conf, logger)
 	transport := newAuthTransport(
 		retryTrans,
 		newAuthClient(conf, retryTrans),
@@ -80,7 +90,8 @@ func NewAPI(ctx context.Context, conf config.Trakt, logger *slog.Logger) (API, e
 		httpClient: &http.Client{
 			Transport: transport,
 		},
-		logger: logger,
+		logger:          logger,
+		listParallelism: *conf.ListParallelism,
 	}
 	ui, err := c.getUserInfo(ctx)
 	if err != nil {
@@ -93,63 +104,154 @@ func NewAPI(ctx context.Context, conf config.Trakt, logger *slog.Logger) (API, e
 // postItemsChunked splits its into chunks of at most bulkRequestChunkSize
 // and POSTs each chunk to path in turn, merging the per-chunk responses into
 // a single result so callers can log/handle it as if it were one request.
+// Episodes that trakt could not match by their ids are sent once more by their
+// show and numbers, and the ones that can't be matched either way are reported
+// as not found by their ids.
 func (c *client) postItemsChunked(ctx context.Context, path string, its Items, statusCode int) (response, error) {
+	merged, err := c.postChunks(ctx, path, its, statusCode, false)
+	if err != nil {
+		return response{}, err
+	}
+	resolvable, unmatched := its.byNumber(merged.NotFound)
+	for _, it := range unmatched {
+		c.logger.Warn("trakt could not match episode, and its show and numbers are unknown", "imdbID", it.Episode.IDMeta.IMDb, "title", it.Episode.Title)
+	}
+	if len(resolvable) == 0 {
+		return merged, nil
+	}
+	merged.NotFound.Episodes = slices.DeleteFunc(merged.NotFound.Episodes, func(spec ItemSpec) bool {
+		return slices.ContainsFunc(resolvable, func(it Item) bool { return it.Episode.IDMeta == spec.IDMeta })
+	})
+	c.logger.Info("sending episodes that trakt could not match by id by their show and numbers", "count", len(resolvable))
+	r, err := c.postChunks(ctx, path, resolvable, statusCode, true)
+	if err != nil {
+		return response{}, err
+	}
+	unresolved := resolvable.unmatchedByNumber(r.NotFound)
+	r.NotFound = nil
+	merged.merge(r)
+	if len(unresolved) == 0 {
+		return merged, nil
+	}
+	episodes := make(ItemSpecs, 0, len(unresolved))
+	for _, it := range unresolved {
+		c.logger.Warn("trakt could not match episode by its show and numbers", "imdbID", it.Episode.IDMeta.IMDb, "showIMDbID", it.Show.IDMeta.IMDb, "season", deref(it.Episode.Season), "episode", deref(it.Episode.Number))
+		episodes = append(episodes, it.Episode)
+	}
+	merged.NotFound = mergeListBody(merged.NotFound, &listBody{Episodes: episodes})
+	return merged, nil
+}
+
+func (c *client) postChunks(ctx context.Context, path string, its Items, statusCode int, byNumber bool) (response, error) {
 	merged := response{}
 	for chunk := range slices.Chunk(its, bulkRequestChunkSize) {
-		b, err := json.Marshal(chunk.toListBody())
-		if err != nil {
-			return response{}, fmt.Errorf("failure marshaling items: %w", err)
-		}
-		resp, err := doRequest(ctx, c.httpClient, http.MethodPost, c.baseURL, path, nil, bytes.NewReader(b), nil, statusCode)
+		r, err := c.postBody(ctx, path, chunk.toListBody(byNumber), statusCode)
 		if err != nil {
-			return response{}, fmt.Errorf("failure doing request: %w", err)
-		}
-		r, err := decodeJSON[response](resp.Body)
-		if err != nil {
-			return response{}, fmt.Errorf("failure decoding response: %w", err)
+			return response{}, err
 		}
 		merged.merge(r)
 	}
 	return merged, nil
 }
 
-func (c *client) HistoryAdd(ctx context.Context, its Items) error {
+func (c *client) postBody(ctx context.Context, path string, lb listBody, statusCode int) (response, error) {
+	b, err := json.Marshal(lb)
+	if err != nil {
+		return response{}, fmt.Errorf("failure marshaling items: %w", err)
+	}
+	resp, err := doRequest(ctx, c.httpClient, http.MethodPost, c.baseURL, path, nil, bytes.NewReader(b), nil, statusCode)
+	if err != nil {
+		return response{}, fmt.Errorf("failure doing request: %w", err)
+	}
+	r, err := decodeJSON[response](resp.Body)
+	if err != nil {
+		return response{}, fmt.Errorf("failure decoding response: %w", err)
+	}
+	return r, nil
+}
+
+func (c *client) HistoryAdd(ctx context.Context, its Items) (Items, error) {
 	h, err := c.postItemsChunked(ctx, pathHistory, its, http.StatusCreated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	c.logger.Info("added trakt history", "items", h)
-	return nil
+	return its.notFound(h.NotFound), nil
 }
 
 func (c *client) HistoryGet(ctx context.Context, itType, itID string) (Items, error) {
 	path := fmt.Sprintf(pathHistoryGet, itType+"s", itID)
-	query := map[string][]string{"limit": {"1000"}}
-	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, path, query, http.NoBody, nil, http.StatusOK, http.StatusNotFound)
+	h, err := doPaginatedRequest[Item](ctx, c.httpClient, c.baseURL, path, nil, http.StatusOK, http.StatusNotFound)
 	if err != nil {
-		return nil, fmt.Errorf("failure doing request: %w", err)
+		return nil, fmt.Errorf("failure fetching history: %w", err)
 	}
-	h, err := decodeJSON[Items](resp.Body)
+	return h, nil
+}
+
+// HistoryGetAll fetches every history entry of the user, page by page.
+func (c *client) HistoryGetAll(ctx context.Context) (Items, error) {
+	history, err := doPaginatedRequest[Item](ctx, c.httpClient, c.baseURL, pathHistory, nil, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failure decoding history response: %w", err)
+		return nil, fmt.Errorf("failure fetching history: %w", err)
 	}
-	return h, nil
+	return history, nil
 }
 
+// HistoryRemove removes every play of the given items from history, except for
+// the items that carry the id of a single play, which only that play is
+// removed of.
 func (c *client) HistoryRemove(ctx context.Context, its Items) error {
-	h, err := c.postItemsChunked(ctx, pathHistoryRemove, its, http.StatusOK)
+	playIDs := make([]int64, 0)
+	items := make(Items, 0, len(its))
+	for _, it := range its {
+		if it.ID != 0 {
+			playIDs = append(playIDs, it.ID)
+			continue
+		}
+		items = append(items, it)
+	}
+	h, err := c.postItemsChunked(ctx, pathHistoryRemove, items, http.StatusOK)
 	if err != nil {
 		return err
 	}
+	for chunk := range slices.Chunk(playIDs, bulkRequestChunkSize) {
+		r, err := c.postBody(ctx, pathHistoryRemove, listBody{IDs: chunk}, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		h.merge(r)
+	}
 	c.logger.Info("removed trakt history", "items", h)
 	return nil
 }
 
-func (c *client) ListCreate(ctx context.Context, name string) (*IDMeta, error) {
-	b, err := json.Marshal(listAddBody{
-		Name:        name,
+func (c *client) LastActivitiesGet(ctx context.Context) (*LastActivities, error) {
+	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, pathLastActivities, nil, http.NoBody, nil, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("failure doing request: %w", err)
+	}
+	la, err := decodeJSON[LastActivities](resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failure decoding last activities response: %w", err)
+	}
+	return &la, nil
+}
+
+// ListCreate creates a list named after list.Name, with the description and
+// privacy of list. Lists without a description are described as imported from
+// imdb, and lists without a privacy get the trakt default.
+func (c *client) ListCreate(ctx context.Context, list List) (*IDMeta, error) {
+	lab := listAddBody{
+		Name:        *list.Name,
 		Description: fmt.Sprintf("List imported from IMDb using https://github.com/cecobask/imdb-trakt-sync on %v", time.Now().Format(time.RFC1123)),
-	})
+	}
+	if list.Description != nil {
+		lab.Description = *list.Description
+	}
+	if list.Privacy != nil {
+		lab.Privacy = *list.Privacy
+	}
+	b, err := json.Marshal(lab)
 	if err != nil {
 		return nil, fmt.Errorf("failure marshaling list add body: %w", err)
 	}
@@ -160,13 +262,13 @@ func (c *client) ListCreate(ctx context.Context, name string) (*IDMeta, error) {
 		return nil, fmt.Errorf("failure doing request: %w", err)
 	}
 	defer resp.Body.Close()
-	var list List
-	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+	var created List
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
 		return nil, fmt.Errorf("failure decoding list response: %w", err)
 	}
 
-	c.logger.Info("created trakt list", "name", name, "id", list.IDMeta.Trakt, "slug", list.IDMeta.Slug)
-	return &list.IDMeta, nil
+	c.logger.Info("created trakt list", "name", lab.Name, "id", created.IDMeta.Trakt, "slug", created.IDMeta.Slug)
+	return &created.IDMeta, nil
 }
 
 func (c *client) ListGet(ctx context.Context, lid int) (*List, error) {
@@ -175,13 +277,9 @@ func (c *client) ListGet(ctx context.Context, lid int) (*List, error) {
 		return nil, fmt.Errorf("failure getting list meta: %w", err)
 	}
 	path := fmt.Sprintf(pathUserListItems, c.username, lid)
-	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, path, nil, http.NoBody, nil, http.StatusOK)
-	if err != nil {
-		return nil, fmt.Errorf("failure doing request: %w", err)
-	}
-	litems, err := decodeJSON[Items](resp.Body)
+	litems, err := doPaginatedRequest[Item](ctx, c.httpClient, c.baseURL, path, nil, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failure decoding list response: %w", err)
+		return nil, fmt.Errorf("failure fetching list items: %w", err)
 	}
 	list.ListItems = litems
 	return list, nil
@@ -203,14 +301,14 @@ func (c *client) ListGetMeta(ctx context.Context, lid int) (*List, error) {
 	return &list, nil
 }
 
-func (c *client) ListItemsAdd(ctx context.Context, lid int, name string, its Items) error {
+func (c *client) ListItemsAdd(ctx context.Context, lid int, name string, its Items) (Items, error) {
 	path := fmt.Sprintf(pathUserListItems, c.username, lid)
 	l, err := c.postItemsChunked(ctx, path, its, http.StatusCreated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	c.logger.Info("added trakt list items", "name", name, "items", l)
-	return nil
+	return its.notFound(l.NotFound), nil
 }
 
 func (c *client) ListItemsRemove(ctx context.Context, lid int, name string, its Items) error {
@@ -223,52 +321,77 @@ func (c *client) ListItemsRemove(ctx context.Context, lid int, name string, its
 	return nil
 }
 
+// ListsGet fetches the given lists on a pool of TRAKT_LISTPARALLELISM workers,
+// and returns them in the order of their ids. The first failure cancels the
+// lists that are still to be fetched, and the failures are reported together.
 func (c *client) ListsGet(ctx context.Context, ids IDMetas) (Lists, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	var (
-		outChan  = make(chan List, len(ids))
-		errChan  = make(chan error, 1)
-		doneChan = make(chan struct{})
-		lists    = make(Lists, 0, len(ids))
+		lists     = make(Lists, len(ids))
+		errs      = make([]error, len(ids))
+		indexes   = make(chan int)
+		waitGroup = new(sync.WaitGroup)
 	)
-	go func() {
-		waitGroup := new(sync.WaitGroup)
-		for _, id := range ids {
-			waitGroup.Add(1)
-			go func(id IDMeta) {
-				defer waitGroup.Done()
-				list, err := c.ListGet(ctx, id.Trakt)
+	for range min(c.listParallelism, len(ids)) {
+		waitGroup.Go(func() {
+			for i := range indexes {
+				list, err := c.ListGet(ctx, ids[i].Trakt)
 				if err != nil {
-					errChan <- fmt.Errorf("unexpected error while fetching lists: %w", err)
-					return
+					errs[i] = fmt.Errorf("failure fetching list %d: %w", ids[i].Trakt, err)
+					cancel()
+					continue
 				}
-				list.IDMeta = id
-				outChan <- *list
-			}(id)
-		}
-		waitGroup.Wait()
-		close(doneChan)
-	}()
-	for {
+				list.IDMeta = ids[i]
+				lists[i] = *list
+			}
+		})
+	}
+feed:
+	for i := range ids {
 		select {
-		case list := <-outChan:
-			lists = append(lists, list)
-		case err := <-errChan:
-			return nil, err
-		case <-doneChan:
-			return lists, nil
+		case indexes <- i:
+		case <-ctx.Done():
+			// the lists that weren't handed out fail with the cancellation
+			for j := i; j < len(ids); j++ {
+				errs[j] = fmt.Errorf("failure fetching list %d: %w", ids[j].Trakt, ctx.Err())
+			}
+			break feed
+		}
+	}
+	close(indexes)
+	waitGroup.Wait()
+	if err := listsError(errs); err != nil {
+		return nil, fmt.Errorf("unexpected error while fetching lists: %w", err)
+	}
+	return lists, nil
+}
+
+// listsError joins the failures of ListsGet, leaving out the requests that were
+// only cancelled because of another failure.
+func listsError(errs []error) error {
+	failures := make([]error, 0)
+	cancellations := make([]error, 0)
+	for _, err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, context.Canceled):
+			cancellations = append(cancellations, err)
+		default:
+			failures = append(failures, err)
 		}
 	}
+	if len(failures) == 0 {
+		return errors.Join(cancellations...)
+	}
+	return errors.Join(failures...)
 }
 
 func (c *client) ListsGetAllMeta(ctx context.Context) (Lists, error) {
 	path := fmt.Sprintf(pathUserLists, c.username)
-	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, path, nil, http.NoBody, nil, http.StatusOK)
-	if err != nil {
-		return nil, fmt.Errorf("failure doing request: %w", err)
-	}
-	lists, err := decodeJSON[Lists](resp.Body)
+	lists, err := doPaginatedRequest[List](ctx, c.httpClient, c.baseURL, path, nil, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failure decoding lists response: %w", err)
+		return nil, fmt.Errorf("failure fetching lists: %w", err)
 	}
 	listNames := make([]string, 0, len(lists))
 	for _, list := range lists {
@@ -279,23 +402,19 @@ func (c *client) ListsGetAllMeta(ctx context.Context) (Lists, error) {
 	return lists, nil
 }
 
-func (c *client) RatingsAdd(ctx context.Context, its Items) error {
+func (c *client) RatingsAdd(ctx context.Context, its Items) (Items, error) {
 	r, err := c.postItemsChunked(ctx, pathRatings, its, http.StatusCreated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	c.logger.Info("added trakt ratings", "items", r)
-	return nil
+	return its.notFound(r.NotFound), nil
 }
 
 func (c *client) RatingsGet(ctx context.Context) (Items, error) {
-	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, pathRatings, nil, http.NoBody, nil, http.StatusOK)
-	if err != nil {
-		return nil, fmt.Errorf("failure doing request: %w", err)
-	}
-	r, err := decodeJSON[Items](resp.Body)
+	r, err := doPaginatedRequest[Item](ctx, c.httpClient, c.baseURL, pathRatings, nil, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failure decoding ratings response: %w", err)
+		return nil, fmt.Errorf("failure fetching ratings: %w", err)
 	}
 	return r, nil
 }
@@ -309,14 +428,30 @@ func (c *client) RatingsRemove(ctx context.Context, its Items) error {
 	return nil
 }
 
-func (c *client) WatchlistGet(ctx context.Context) (*List, error) {
-	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, pathWatchlist, nil, http.NoBody, nil, http.StatusOK)
+// Search looks up the items trakt knows by an id, where idType is one of imdb,
+// tmdb, tvdb or trakt. The ids of tmdb and tvdb are only unique per type, so
+// itType narrows the search down to a single type unless it is empty.
+func (c *client) Search(ctx context.Context, idType, id, itType string) (Items, error) {
+	path := fmt.Sprintf(pathSearch, idType, id)
+	var query map[string][]string
+	if itType != "" {
+		query = map[string][]string{"type": {itType}}
+	}
+	resp, err := doRequest(ctx, c.httpClient, http.MethodGet, c.baseURL, path, query, http.NoBody, nil, http.StatusOK)
 	if err != nil {
 		return nil, fmt.Errorf("failure doing request: %w", err)
 	}
-	witems, err := decodeJSON[Items](resp.Body)
+	results, err := decodeJSON[Items](resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failure decoding search response: %w", err)
+	}
+	return results, nil
+}
+
+func (c *client) WatchlistGet(ctx context.Context) (*List, error) {
+	witems, err := doPaginatedRequest[Item](ctx, c.httpClient, c.baseURL, pathWatchlist, nil, http.StatusOK)
 	if err != nil {
-		return nil, fmt.Errorf("failure decoding watchlist response: %w", err)
+		return nil, fmt.Errorf("failure fetching watchlist: %w", err)
 	}
 	return &List{
 		IDMeta: IDMeta{
@@ -327,13 +462,13 @@ func (c *client) WatchlistGet(ctx context.Context) (*List, error) {
 	}, nil
 }
 
-func (c *client) WatchlistItemsAdd(ctx context.Context, its Items) error {
+func (c *client) WatchlistItemsAdd(ctx context.Context, its Items) (Items, error) {
 	w, err := c.postItemsChunked(ctx, pathWatchlist, its, http.StatusCreated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	c.logger.Info("added trakt watchlist items", "items", w)
-	return nil
+	return its.notFound(w.NotFound), nil
 }
 
 func (c *client) WatchlistItemsRemove(ctx context.Context, its Items) error {
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"net/url"
 	"slices"
+	"strconv"
 )
 
 func doRequest(ctx context.Context, client *http.Client, method, baseURL, path string, query map[string][]string, body io.Reader, headers map[string]string, statusCodes ...int) (*http.Response, error) {
@@ -22,7 +24,7 @@ func doRequest(ctx context.Context, client *http.Client, method, baseURL, path s
 		}
 	}
 	u.RawQuery = q.Encode()
-	req, err := http.NewRequestWithContext(ctx, method, u.String(), NewReader(body))
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
 	if err != nil {
 		return nil, fmt.Errorf("failure creating http request: %w", err)
 	}
@@ -39,3 +41,32 @@ func doRequest(ctx context.Context, client *http.Client, method, baseURL, path s
 	}
 	return resp, nil
 }
+
+// doPaginatedRequest requests every page of a list endpoint and decodes them
+// into a single slice, so that large lists aren't silently truncated to their
+// first page. The page count is read from the pagination headers. Endpoints
+// that don't paginate send no page count, which stops after the first page.
+func doPaginatedRequest[T any](ctx context.Context, client *http.Client, baseURL, path string, query map[string][]string, statusCodes ...int) ([]T, error) {
+	all := make([]T, 0)
+	for page := 1; ; page++ {
+		pageQuery := maps.Clone(query)
+		if pageQuery == nil {
+			pageQuery = make(map[string][]string)
+		}
+		pageQuery["page"] = []string{strconv.Itoa(page)}
+		pageQuery["limit"] = []string{strconv.Itoa(paginationLimit)}
+		resp, err := doRequest(ctx, client, http.MethodGet, baseURL, path, pageQuery, http.NoBody, nil, statusCodes...)
+		if err != nil {
+			return nil, err
+		}
+		pageCount, _ := strconv.Atoi(resp.Header.Get(headerPaginationPageCount))
+		items, err := decodeJSON[[]T](resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if page >= pageCount {
+			return all, nil
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestDoPaginatedRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		pages      [][]int
+		pageCount  string
+		statusCode int
+		want       []int
+		wantPages  int
+		wantErr    bool
+	}{
+		{
+			name:       "every page is read",
+			pages:      [][]int{{1, 2}, {3, 4}, {5}},
+			pageCount:  "3",
+			statusCode: http.StatusOK,
+			want:       []int{1, 2, 3, 4, 5},
+			wantPages:  3,
+		},
+		{
+			name:       "single page",
+			pages:      [][]int{{1, 2}},
+			pageCount:  "1",
+			statusCode: http.StatusOK,
+			want:       []int{1, 2},
+			wantPages:  1,
+		},
+		{
+			name:       "endpoints without pagination headers stop after the first page",
+			pages:      [][]int{{1, 2}, {3}},
+			statusCode: http.StatusOK,
+			want:       []int{1, 2},
+			wantPages:  1,
+		},
+		{
+			name:       "empty page",
+			pages:      [][]int{{}},
+			pageCount:  "0",
+			statusCode: http.StatusOK,
+			want:       []int{},
+			wantPages:  1,
+		},
+		{
+			name:       "unexpected status code",
+			pages:      [][]int{{1}},
+			pageCount:  "1",
+			statusCode: http.StatusNotFound,
+			wantPages:  1,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requested int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested++
+				if got := r.URL.Query().Get("limit"); got != strconv.Itoa(paginationLimit) {
+					t.Errorf("limit = %s, want %d", got, paginationLimit)
+				}
+				page, err := strconv.Atoi(r.URL.Query().Get("page"))
+				if err != nil || page < 1 || page > len(tt.pages) {
+					t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				if tt.pageCount != "" {
+					w.Header().Set(headerPaginationPageCount, tt.pageCount)
+				}
+				w.WriteHeader(tt.statusCode)
+				_ = json.NewEncoder(w).Encode(tt.pages[page-1])
+			}))
+			defer server.Close()
+			got, err := doPaginatedRequest[int](context.Background(), server.Client(), server.URL, "items", nil, http.StatusOK)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("doPaginatedRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var statusErr *UnexpectedStatusCodeError
+				if !errors.As(err, &statusErr) {
+					t.Errorf("doPaginatedRequest() error = %v, want %T", err, statusErr)
+				}
+			} else if !slices.Equal(got, tt.want) {
+				t.Errorf("doPaginatedRequest() = %v, want %v", got, tt.want)
+			}
+			if requested != tt.wantPages {
+				t.Errorf("requested %d pages, want %d", requested, tt.wantPages)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package trakt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	headerRateLimit = "X-Ratelimit"
+
+	// trakt allows 1000 reads per 5 minutes and a write per second
+	rateLimitGetPeriod   = time.Minute * 5
+	rateLimitGetRequests = 1000
+	rateLimitGetBurst    = 10
+	rateLimitWritePeriod = time.Second
+)
+
+// rateLimitTransport holds requests back until trakt's rate limits allow them,
+// so that concurrent requests don't run into 429 responses in the first place.
+// Reads and writes are limited separately, just like trakt limits them.
+type rateLimitTransport struct {
+	next   http.RoundTripper
+	get    *bucket
+	writes *bucket
+}
+
+func newRateLimitTransport(next http.RoundTripper) *rateLimitTransport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &rateLimitTransport{
+		next:   next,
+		get:    newBucket(rateLimitGetRequests/rateLimitGetPeriod.Seconds(), rateLimitGetBurst),
+		writes: newBucket(1/rateLimitWritePeriod.Seconds(), 1),
+	}
+}
+
+func (rt *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b := rt.writes
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		b = rt.get
+	}
+	if err := b.wait(req.Context()); err != nil {
+		return nil, fmt.Errorf("failure waiting for rate limit: %w", err)
+	}
+	resp, err := rt.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	b.update(resp.Header)
+	return resp, nil
+}
+
+// rateLimit is the value of the X-Ratelimit header, which tells how many
+// requests are left until the limit resets.
+type rateLimit struct {
+	Name      string    `json:"name"`
+	Period    int       `json:"period"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Until     time.Time `json:"until"`
+}
+
+// bucket is a token bucket, which refills at rate tokens per second up to
+// burst tokens. A request takes a token, or waits until there is one.
+type bucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	// until holds requests back while trakt reports the limit as exhausted
+	until time.Time
+}
+
+func newBucket(rate, burst float64) *bucket {
+	return &bucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait takes a token, waiting for one as long as ctx allows.
+func (b *bucket) wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		var delay time.Duration
+		switch {
+		case now.Before(b.until):
+			delay = b.until.Sub(now)
+		case b.tokens >= 1:
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		default:
+			delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		}
+		b.mu.Unlock()
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// update holds the following requests back until the limit resets, when the
+// response says that none are left.
+func (b *bucket) update(header http.Header) {
+	value := header.Get(headerRateLimit)
+	if value == "" {
+		return
+	}
+	var limit rateLimit
+	if err := json.Unmarshal([]byte(value), &limit); err != nil || limit.Remaining > 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if limit.Until.After(b.until) {
+		b.until = limit.Until
+	}
+}
+
+// sleep pauses for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -1,41 +1,58 @@
 package trakt
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"strconv"
+	"syscall"
 	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/config"
 )
 
 type retryTransport struct {
-	next   http.RoundTripper
-	logger *slog.Logger
+	next    http.RoundTripper
+	backoff backoff
+	logger  *slog.Logger
 }
 
-func newRetryTransport(next http.RoundTripper, logger *slog.Logger) *retryTransport {
+func newRetryTransport(next http.RoundTripper, conf config.Trakt, logger *slog.Logger) *retryTransport {
 	if next == nil {
 		next = http.DefaultTransport
 	}
 	return &retryTransport{
-		next:   next,
-		logger: logger,
+		next:    next,
+		backoff: newBackoff(conf),
+		logger:  logger,
 	}
 }
 
 func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	for attempt := 1; attempt <= 5; attempt++ {
-		resp, err := rt.next.RoundTrip(req)
+	for attempt := 1; ; attempt++ {
+		var (
+			reason   string
+			duration time.Duration
+		)
+		attemptReq, err := rewind(req, attempt)
 		if err != nil {
-			return nil, fmt.Errorf("failure sending http request: %w", err)
+			return nil, fmt.Errorf("failure rewinding http request body: %w", err)
 		}
-		if resp.StatusCode == 420 {
+		resp, err := rt.next.RoundTrip(attemptReq)
+		switch {
+		case err != nil:
+			if !rt.backoff.retryable(req, err) {
+				return nil, fmt.Errorf("failure sending http request: %w", err)
+			}
+			reason, duration = "network error encountered", rt.backoff.delay(attempt)
+		case resp.StatusCode == 420:
 			resp.Body.Close()
 			return nil, NewAccountLimitExceededError(resp.Header)
-		}
-		if resp.StatusCode == http.StatusTooManyRequests {
+		case resp.StatusCode == http.StatusTooManyRequests:
 			resp.Body.Close()
 			retryAfterHeader, retryAfter := resp.Header.Get("retry-after"), 30
 			if retryAfterHeader != "" {
@@ -43,44 +60,101 @@ func (rt *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 					return nil, fmt.Errorf("failure parsing the value of header 'retry-after' to integer: %w", err)
 				}
 			}
-			duration := time.Duration(retryAfter) * time.Second
-			rt.logger.Error("rate limit reached, retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "backoff", duration.String())
-			time.Sleep(duration)
-			continue
-		}
-		if resp.StatusCode >= http.StatusInternalServerError {
+			reason, duration = "rate limit reached", time.Duration(retryAfter)*time.Second
+		case resp.StatusCode >= http.StatusInternalServerError:
 			resp.Body.Close()
-			duration := time.Second
-			rt.logger.Error("server error encountered, retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "backoff", duration.String())
-			time.Sleep(duration)
-			continue
+			reason, duration = "server error encountered", rt.backoff.delay(attempt)
+		default:
+			return resp, nil
+		}
+		if attempt >= rt.backoff.attempts {
+			if err != nil {
+				return nil, fmt.Errorf("reached max retry attempts for http request %s %s: %s: %w", req.Method, req.URL, reason, err)
+			}
+			return nil, fmt.Errorf("reached max retry attempts for http request %s %s: %s", req.Method, req.URL, reason)
+		}
+		rt.logger.Error(reason+", retrying request", "method", req.Method, "url", req.URL.String(), "attempt", attempt, "backoff", duration.String())
+		if err = sleep(req.Context(), duration); err != nil {
+			return nil, fmt.Errorf("failure waiting to retry http request: %w", err)
 		}
-		return resp, nil
 	}
-	return nil, fmt.Errorf("reached max retry attempts for http request %s %s", req.Method, req.URL)
 }
 
-type rereader struct {
-	io.Reader
-	readBuf *bytes.Buffer
-	backBuf *bytes.Buffer
+// backoff decides whether and when failed requests are retried, as configured
+// by the TRAKT_RETRY* fields. The delay doubles with every attempt, up to the
+// max delay, and is spread by the jitter so that retries don't line up.
+type backoff struct {
+	attempts      int
+	base          time.Duration
+	max           time.Duration
+	jitter        float64
+	networkErrors bool
+}
+
+func newBackoff(conf config.Trakt) backoff {
+	return backoff{
+		attempts:      *conf.RetryAttempts,
+		base:          *conf.RetryBaseDelay,
+		max:           *conf.RetryMaxDelay,
+		jitter:        *conf.RetryJitter,
+		networkErrors: *conf.RetryNetworkErrors,
+	}
+}
+
+func (b backoff) delay(attempt int) time.Duration {
+	d := b.base
+	for i := 1; i < attempt && d < b.max; i++ {
+		d *= 2
+	}
+	d = min(d, b.max)
+	spread := 1 + b.jitter*(2*rand.Float64()-1)
+	return min(time.Duration(float64(d)*spread), b.max)
+}
+
+// rewind returns the request to send on the given attempt. Every attempt after
+// the first one gets a fresh copy of the body, since the previous attempt has
+// consumed it.
+func rewind(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 1 || req.Body == nil || req.Body == http.NoBody {
+		return req, nil
+	}
+	if req.GetBody == nil {
+		return nil, errors.New("request body can't be read again")
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	r := req.Clone(req.Context())
+	r.Body = body
+	return r, nil
 }
 
-func NewReader(r io.Reader) io.Reader {
-	readBuf := bytes.Buffer{}
-	readBuf.ReadFrom(r)
-	backBuf := bytes.Buffer{}
-	return rereader{
-		Reader:  io.TeeReader(&readBuf, &backBuf),
-		readBuf: &readBuf,
-		backBuf: &backBuf,
+// retryable reports whether the request failed on a transient network error,
+// such as a connection that was reset or timed out. Requests that change data
+// are only retried when they failed to connect, since trakt may have applied
+// them before the connection broke. Nothing is retried once the context of the
+// request is done.
+func (b backoff) retryable(req *http.Request, err error) bool {
+	if !b.networkErrors || req.Context().Err() != nil {
+		return false
+	}
+	if !idempotent(req.Method) {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
 	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
 
-func (r rereader) Read(p []byte) (int, error) {
-	n, err := r.Reader.Read(p)
-	if err == io.EOF {
-		io.Copy(r.readBuf, r.backBuf)
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
 	}
-	return n, err
 }
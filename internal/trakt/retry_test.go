@@ -0,0 +1,141 @@
+package trakt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/logger"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestBackoff_retryable(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		method        string
+		err           error
+		networkErrors bool
+		want          bool
+	}{
+		{
+			name:          "connection reset on a read",
+			method:        http.MethodGet,
+			err:           fmt.Errorf("wrapped: %w", readErr),
+			networkErrors: true,
+			want:          true,
+		},
+		{
+			name:          "unexpected eof on a read",
+			method:        http.MethodGet,
+			err:           io.ErrUnexpectedEOF,
+			networkErrors: true,
+			want:          true,
+		},
+		{
+			name:          "timeout on a read",
+			method:        http.MethodGet,
+			err:           timeoutError{},
+			networkErrors: true,
+			want:          true,
+		},
+		{
+			name:          "other errors on a read",
+			method:        http.MethodGet,
+			err:           os.ErrPermission,
+			networkErrors: true,
+			want:          false,
+		},
+		{
+			name:          "connection reset on a write",
+			method:        http.MethodPost,
+			err:           readErr,
+			networkErrors: true,
+			want:          false,
+		},
+		{
+			name:          "dial failure on a write",
+			method:        http.MethodPost,
+			err:           dialErr,
+			networkErrors: true,
+			want:          true,
+		},
+		{
+			name:          "network errors disabled",
+			method:        http.MethodGet,
+			err:           readErr,
+			networkErrors: false,
+			want:          false,
+		},
+		{
+			name:          "context done",
+			ctx:           canceled,
+			method:        http.MethodGet,
+			err:           readErr,
+			networkErrors: true,
+			want:          false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			req, err := http.NewRequestWithContext(ctx, tt.method, "https://api.trakt.tv", http.NoBody)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b := backoff{networkErrors: tt.networkErrors}
+			if got := b.retryable(req, tt.err); got != tt.want {
+				t.Errorf("retryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransport_RoundTrip(t *testing.T) {
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: syscall.ECONNRESET}
+	var attempts int
+	rt := &retryTransport{
+		next: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			attempts++
+			return nil, readErr
+		}),
+		backoff: backoff{attempts: 3, base: time.Millisecond, max: time.Millisecond, networkErrors: true},
+		logger:  logger.NewLogger(io.Discard),
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://api.trakt.tv", http.NoBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rt.RoundTrip(req)
+	if attempts != 3 {
+		t.Errorf("sent %d attempts, want 3", attempts)
+	}
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("RoundTrip() error = %v, want it to wrap %v", err, syscall.ECONNRESET)
+	}
+}
@@ -2,7 +2,10 @@ package trakt
 
 import (
 	"fmt"
+	"slices"
 	"time"
+
+	"github.com/cecobask/imdb-trakt-sync/internal/media"
 )
 
 const (
@@ -11,6 +14,8 @@ const (
 	ItemTypeSeason  = "season"
 	ItemTypeShow    = "show"
 	ItemTypePerson  = "person"
+
+	watchedAtUnknown = "unknown"
 )
 
 type CrudItem struct {
@@ -23,20 +28,25 @@ type CrudItem struct {
 type IDMeta struct {
 	IMDb  string `json:"imdb,omitempty"`
 	Slug  string `json:"slug,omitempty"`
+	TMDb  int    `json:"tmdb,omitempty"`
+	TVDb  int    `json:"tvdb,omitempty"`
 	Trakt int    `json:"trakt,omitempty"`
 }
 
 type IDMetas []IDMeta
 
 type Item struct {
-	Type    string    `json:"type"`
-	RatedAt string    `json:"rated_at,omitempty"`
-	Rating  float64   `json:"rating,omitempty"`
-	Movie   ItemSpec  `json:"movie,omitempty"`
-	Show    ItemSpec  `json:"show,omitempty"`
-	Episode ItemSpec  `json:"episode,omitempty"`
-	Person  ItemSpec  `json:"person,omitempty"`
-	Created time.Time `json:"-"`
+	ID        int64     `json:"id,omitempty"`
+	Type      string    `json:"type"`
+	RatedAt   string    `json:"rated_at,omitempty"`
+	Rating    float64   `json:"rating,omitempty"`
+	WatchedAt string    `json:"watched_at,omitempty"`
+	Movie     ItemSpec  `json:"movie,omitempty"`
+	Show      ItemSpec  `json:"show,omitempty"`
+	Season    ItemSpec  `json:"season,omitempty"`
+	Episode   ItemSpec  `json:"episode,omitempty"`
+	Person    ItemSpec  `json:"person,omitempty"`
+	Created   time.Time `json:"-"`
 }
 
 func (it *Item) GetItemID() (*string, error) {
@@ -56,18 +66,257 @@ func (it *Item) GetItemID() (*string, error) {
 	}
 }
 
+// GetItemSpec returns the spec that holds the ids and title of the item,
+// or nil when the item type carries no spec of its own.
+func (it *Item) GetItemSpec() *ItemSpec {
+	switch it.Type {
+	case ItemTypeMovie:
+		return &it.Movie
+	case ItemTypeShow:
+		return &it.Show
+	case ItemTypeSeason:
+		return &it.Season
+	case ItemTypeEpisode:
+		return &it.Episode
+	case ItemTypePerson:
+		return &it.Person
+	default:
+		return nil
+	}
+}
+
+// Restorable copies the rating and watch date of an item fetched from trakt
+// into its spec, which is where trakt expects them when the item is sent back.
+func (it Item) Restorable() Item {
+	spec := it.GetItemSpec()
+	if spec == nil {
+		return it
+	}
+	if spec.Rating == nil && it.Rating != 0 {
+		rating := it.Rating
+		spec.Rating = &rating
+		if it.RatedAt != "" {
+			ratedAt := it.RatedAt
+			spec.RatedAt = &ratedAt
+		}
+	}
+	if spec.WatchedAt == nil && it.WatchedAt != "" {
+		watchedAt := it.WatchedAt
+		spec.WatchedAt = &watchedAt
+	}
+	return it
+}
+
+// ToMedia converts the item to the provider-neutral model. Items fetched from
+// trakt carry their rating and dates on the item, while items that are sent to
+// trakt carry them on the spec, hence both are looked at.
+func (it Item) ToMedia() media.Item {
+	mi := media.Item{
+		Type:      media.Type(it.Type),
+		CreatedAt: it.Created,
+	}
+	spec := it.GetItemSpec()
+	if spec == nil {
+		return mi
+	}
+	mi.IDs = media.IDs{
+		IMDb:  spec.IDMeta.IMDb,
+		TMDb:  spec.IDMeta.TMDb,
+		TVDb:  spec.IDMeta.TVDb,
+		Trakt: spec.IDMeta.Trakt,
+		Slug:  spec.IDMeta.Slug,
+	}
+	mi.Title, mi.Year = spec.Title, spec.Year
+	switch it.Type {
+	case ItemTypePerson:
+		mi.Title = spec.Name
+	case ItemTypeSeason:
+		mi.Show = showIDs(it.Show)
+		mi.Season = deref(spec.Number)
+	case ItemTypeEpisode:
+		mi.Show = showIDs(it.Show)
+		mi.Season, mi.Episode = deref(spec.Season), deref(spec.Number)
+	}
+	switch {
+	case it.Rating != 0:
+		rating := it.Rating
+		mi.Rating = &rating
+	case spec.Rating != nil:
+		rating := *spec.Rating
+		mi.Rating = &rating
+	}
+	mi.RatedAt = parseTime(it.RatedAt, spec.RatedAt)
+	mi.WatchedAt = parseTime(it.WatchedAt, spec.WatchedAt)
+	if it.WatchedAt != "" {
+		// only history entries are watched, the ids of other items are list entries
+		mi.PlayID = it.ID
+	}
+	return mi
+}
+
+// NewItemFromMedia is the inverse of ToMedia. The rating and dates go into the
+// spec, which is where trakt expects them when an item is sent to it. The title
+// and year are left out, so that trakt matches the item by its ids only.
+func NewItemFromMedia(mi media.Item) Item {
+	it := Item{
+		ID:      mi.PlayID,
+		Type:    string(mi.Type),
+		Created: mi.CreatedAt,
+	}
+	spec := ItemSpec{
+		IDMeta: IDMeta{
+			IMDb:  mi.IDs.IMDb,
+			Slug:  mi.IDs.Slug,
+			TMDb:  mi.IDs.TMDb,
+			TVDb:  mi.IDs.TVDb,
+			Trakt: mi.IDs.Trakt,
+		},
+	}
+	if mi.Rating != nil {
+		rating := *mi.Rating
+		spec.Rating = &rating
+	}
+	spec.RatedAt = formatTime(mi.RatedAt)
+	spec.WatchedAt = formatWatchedAt(mi.WatchedAt)
+	if mi.Show != nil {
+		it.Show = ItemSpec{
+			IDMeta: IDMeta{
+				IMDb:  mi.Show.IMDb,
+				Slug:  mi.Show.Slug,
+				TMDb:  mi.Show.TMDb,
+				TVDb:  mi.Show.TVDb,
+				Trakt: mi.Show.Trakt,
+			},
+		}
+	}
+	switch mi.Type {
+	case media.TypeShow:
+		it.Show = spec
+	case media.TypeSeason:
+		spec.Number = number(mi.Season)
+		it.Season = spec
+	case media.TypeEpisode:
+		spec.Season, spec.Number = number(mi.Season), number(mi.Episode)
+		it.Episode = spec
+	case media.TypePerson:
+		it.Person = spec
+	default:
+		it.Type = ItemTypeMovie
+		it.Movie = spec
+	}
+	return it
+}
+
+func showIDs(show ItemSpec) *media.IDs {
+	if show.IDMeta == (IDMeta{}) {
+		return nil
+	}
+	return &media.IDs{
+		IMDb:  show.IDMeta.IMDb,
+		TMDb:  show.IDMeta.TMDb,
+		TVDb:  show.IDMeta.TVDb,
+		Trakt: show.IDMeta.Trakt,
+		Slug:  show.IDMeta.Slug,
+	}
+}
+
+func deref(n *int) int {
+	if n == nil {
+		return 0
+	}
+	return *n
+}
+
+// number returns nil for unknown numbers, which are 0 in the media model.
+func number(n int) *int {
+	if n == 0 {
+		return nil
+	}
+	return &n
+}
+
+// parseTime parses the date of an item, falling back to the date on its spec.
+func parseTime(value string, specValue *string) *time.Time {
+	if value == "" && specValue != nil {
+		value = *specValue
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func formatTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339)
+	return &s
+}
+
+// formatWatchedAt formats the watch date of an item, which trakt accepts as
+// unknown when the date is zero.
+func formatWatchedAt(t *time.Time) *string {
+	if t != nil && t.IsZero() {
+		s := watchedAtUnknown
+		return &s
+	}
+	return formatTime(t)
+}
+
 type Items []Item
 
-func (its Items) toListBody() listBody {
+func NewItemsFromMedia(mis media.Items) Items {
+	its := make(Items, 0, len(mis))
+	for _, mi := range mis {
+		its = append(its, NewItemFromMedia(mi))
+	}
+	return its
+}
+
+// toListBody sends episodes by their ids, unless byNumber is set, in which case
+// they are sent by their show and numbers instead. Seasons have no ids that
+// trakt matches on, hence they are always sent by their show and number.
+func (its Items) toListBody(byNumber bool) listBody {
 	lb := listBody{}
+	shows := make(map[IDMeta]int)
+	nest := func(show ItemSpec) *ItemSpec {
+		i, ok := shows[show.IDMeta]
+		if !ok {
+			i = len(lb.Shows)
+			shows[show.IDMeta] = i
+			lb.Shows = append(lb.Shows, ItemSpec{IDMeta: show.IDMeta})
+		}
+		return &lb.Shows[i]
+	}
 	for _, item := range its {
 		switch item.Type {
 		case ItemTypeMovie:
 			lb.Movies = append(lb.Movies, item.Movie)
 		case ItemTypeShow:
 			lb.Shows = append(lb.Shows, item.Show)
+		case ItemTypeSeason:
+			season := item.Season
+			season.IDMeta = IDMeta{}
+			show := nest(item.Show)
+			show.Seasons = append(show.Seasons, season)
 		case ItemTypeEpisode:
-			lb.Episodes = append(lb.Episodes, item.Episode)
+			if !byNumber {
+				lb.Episodes = append(lb.Episodes, item.Episode)
+				continue
+			}
+			episode := item.Episode
+			episode.IDMeta, episode.Season = IDMeta{}, nil
+			show := nest(item.Show)
+			i := slices.IndexFunc(show.Seasons, func(season ItemSpec) bool {
+				return deref(season.Number) == deref(item.Episode.Season)
+			})
+			if i == -1 {
+				i = len(show.Seasons)
+				show.Seasons = append(show.Seasons, ItemSpec{Number: item.Episode.Season})
+			}
+			show.Seasons[i].Episodes = append(show.Seasons[i].Episodes, episode)
 		case ItemTypePerson:
 			lb.People = append(lb.People, item.Person)
 		}
@@ -75,34 +324,173 @@ func (its Items) toListBody() listBody {
 	return lb
 }
 
+// notFound returns the items that trakt could not find. Add requests return
+// the items they were called with, so that callers can tell which ones failed.
+func (its Items) notFound(nf *listBody) Items {
+	if nf == nil {
+		return nil
+	}
+	found := func(specs ItemSpecs, spec ItemSpec) bool {
+		return slices.ContainsFunc(specs, func(other ItemSpec) bool { return other.IDMeta == spec.IDMeta })
+	}
+	notFound := make(Items, 0)
+	for _, item := range its {
+		var missing bool
+		switch item.Type {
+		case ItemTypeMovie:
+			missing = found(nf.Movies, item.Movie)
+		case ItemTypeShow:
+			missing = found(nf.Shows, item.Show)
+		case ItemTypeSeason:
+			// seasons are sent within their show, which is reported when trakt can't find it
+			missing = found(nf.Shows, item.Show)
+		case ItemTypeEpisode:
+			missing = found(nf.Episodes, item.Episode) || item.Show.IDMeta != (IDMeta{}) && found(nf.Shows, item.Show)
+		case ItemTypePerson:
+			missing = found(nf.People, item.Person)
+		}
+		if missing {
+			notFound = append(notFound, item)
+		}
+	}
+	return notFound
+}
+
+// byNumber returns the episodes trakt could not match by their ids, which can
+// still be matched by their show and numbers. The remaining episodes can't be
+// matched at all, hence they are returned separately.
+func (its Items) byNumber(notFound *listBody) (Items, Items) {
+	if notFound == nil || len(notFound.Episodes) == 0 {
+		return nil, nil
+	}
+	resolvable, unmatched := make(Items, 0), make(Items, 0)
+	for _, item := range its {
+		if item.Type != ItemTypeEpisode || !slices.ContainsFunc(notFound.Episodes, func(spec ItemSpec) bool {
+			return spec.IDMeta == item.Episode.IDMeta
+		}) {
+			continue
+		}
+		if item.Show.IDMeta == (IDMeta{}) || item.Episode.Season == nil || item.Episode.Number == nil {
+			unmatched = append(unmatched, item)
+			continue
+		}
+		resolvable = append(resolvable, item)
+	}
+	return resolvable, unmatched
+}
+
+// unmatchedByNumber returns the episodes that trakt could not match by their
+// show and numbers either. Trakt reports the shows it could not find, or nests
+// the seasons and episodes it could not find within their show.
+func (its Items) unmatchedByNumber(notFound *listBody) Items {
+	if notFound == nil || len(notFound.Shows) == 0 {
+		return nil
+	}
+	unmatched := make(Items, 0)
+	for _, item := range its {
+		i := slices.IndexFunc(notFound.Shows, func(show ItemSpec) bool { return show.IDMeta == item.Show.IDMeta })
+		if i == -1 {
+			continue
+		}
+		show := notFound.Shows[i]
+		if len(show.Seasons) > 0 {
+			j := slices.IndexFunc(show.Seasons, func(season ItemSpec) bool {
+				return deref(season.Number) == deref(item.Episode.Season)
+			})
+			if j == -1 {
+				continue
+			}
+			if season := show.Seasons[j]; len(season.Episodes) > 0 && !slices.ContainsFunc(season.Episodes, func(episode ItemSpec) bool {
+				return deref(episode.Number) == deref(item.Episode.Number)
+			}) {
+				continue
+			}
+		}
+		unmatched = append(unmatched, item)
+	}
+	return unmatched
+}
+
 type ItemSpec struct {
 	IDMeta    IDMeta   `json:"ids"`
+	Title     string   `json:"title,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	Year      int      `json:"year,omitempty"`
 	RatedAt   *string  `json:"rated_at,omitempty"`
 	Rating    *float64 `json:"rating,omitempty"`
 	WatchedAt *string  `json:"watched_at,omitempty"`
+	// Season and Number are the numbers of a season or an episode, while
+	// Seasons and Episodes nest them within their show when sending them by number.
+	Season   *int      `json:"season,omitempty"`
+	Number   *int      `json:"number,omitempty"`
+	Seasons  ItemSpecs `json:"seasons,omitempty"`
+	Episodes ItemSpecs `json:"episodes,omitempty"`
 }
 
 type ItemSpecs []ItemSpec
 
 type List struct {
-	Name        *string `json:"name,omitempty"`
-	IDMeta      IDMeta  `json:"ids"`
-	ListItems   Items
-	IsWatchlist bool
+	Name        *string    `json:"name,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	Privacy     *string    `json:"privacy,omitempty"`
+	IDMeta      IDMeta     `json:"ids"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	ListItems   Items      `json:"items,omitempty"`
+	IsWatchlist bool       `json:"is_watchlist,omitempty"`
 }
 
 type Lists []List
 
+// LastActivities tells when each kind of user data was last changed on trakt,
+// which is much cheaper to fetch than the data itself.
+type LastActivities struct {
+	Movies    Activity `json:"movies"`
+	Shows     Activity `json:"shows"`
+	Seasons   Activity `json:"seasons"`
+	Episodes  Activity `json:"episodes"`
+	Lists     Activity `json:"lists"`
+	Watchlist Activity `json:"watchlist"`
+}
+
+type Activity struct {
+	RatedAt       time.Time `json:"rated_at,omitzero"`
+	WatchlistedAt time.Time `json:"watchlisted_at,omitzero"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+}
+
+// RatingsUpdatedAt returns when a rating was last added, changed or removed.
+func (la *LastActivities) RatingsUpdatedAt() time.Time {
+	return latest(la.Movies.RatedAt, la.Shows.RatedAt, la.Seasons.RatedAt, la.Episodes.RatedAt)
+}
+
+// WatchlistUpdatedAt returns when the watchlist was last changed.
+func (la *LastActivities) WatchlistUpdatedAt() time.Time {
+	return latest(la.Watchlist.UpdatedAt, la.Movies.WatchlistedAt, la.Shows.WatchlistedAt, la.Seasons.WatchlistedAt, la.Episodes.WatchlistedAt)
+}
+
+func latest(times ...time.Time) time.Time {
+	var t time.Time
+	for _, other := range times {
+		if other.After(t) {
+			t = other
+		}
+	}
+	return t
+}
+
 type listAddBody struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
+	Privacy     string `json:"privacy,omitempty"`
 }
 
 type listBody struct {
 	Movies   ItemSpecs `json:"movies,omitempty"`
 	Shows    ItemSpecs `json:"shows,omitempty"`
+	Seasons  ItemSpecs `json:"seasons,omitempty"`
 	Episodes ItemSpecs `json:"episodes,omitempty"`
 	People   ItemSpecs `json:"people,omitempty"`
+	IDs      []int64   `json:"ids,omitempty"`
 }
 
 type response struct {
@@ -145,8 +533,10 @@ func mergeListBody(a, b *listBody) *listBody {
 	}
 	a.Movies = append(a.Movies, b.Movies...)
 	a.Shows = append(a.Shows, b.Shows...)
+	a.Seasons = append(a.Seasons, b.Seasons...)
 	a.Episodes = append(a.Episodes, b.Episodes...)
 	a.People = append(a.People, b.People...)
+	a.IDs = append(a.IDs, b.IDs...)
 	return a
 }
 